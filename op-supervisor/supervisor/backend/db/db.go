@@ -14,10 +14,35 @@ import (
 )
 
 const (
+	// searchCheckpointFrequency is the default number of entries between search checkpoints
 	searchCheckpointFrequency = 256
+	// minSearchCheckpointFrequency leaves room for at least one entry after each search checkpoint and canonical hash
+	minSearchCheckpointFrequency = 3
+
+	// entryDataSize is the number of bytes in each entry available to the encoded data.
+	// The remaining bytes hold the checksum.
+	entryDataSize = 24
 
 	eventFlagIncrementLogIdx = byte(1)
 	//eventFlagHasExecutingMessage = byte(1) << 1
+
+	// maxInitiatingEventLogDiff is the largest log idx increment an initiating event can record:
+	// one for the increment flag plus up to 255 skipped logs.
+	maxInitiatingEventLogDiff = math.MaxUint8 + 1
+
+	// maxExecutingLinkLogIdx is the largest log idx that fits in the 3 bytes an executing link records it in.
+	maxExecutingLinkLogIdx = 1<<24 - 1
+)
+
+const (
+	// entryTypeMask selects the entry type from the type byte, the remaining high bits hold the entry version.
+	entryTypeMask     = byte(0x1f)
+	entryVersionShift = 5
+
+	// entryVersion0 is the initial format of all entry types.
+	entryVersion0 = byte(0)
+	// currentEntryVersion is the version new entries are written with.
+	currentEntryVersion = entryVersion0
 )
 
 const (
@@ -26,11 +51,16 @@ const (
 	typeInitiatingEvent
 	typeExecutingLink
 	typeExecutingCheck
+	typeSkip
 )
 
 var (
-	ErrLogOutOfOrder  = errors.New("log out of order")
-	ErrDataCorruption = errors.New("data corruption")
+	ErrLogOutOfOrder      = errors.New("log out of order")
+	ErrDataCorruption     = errors.New("data corruption")
+	ErrUnsupportedVersion = errors.New("unsupported entry version")
+	ErrNotFound           = errors.New("not found")
+
+	ErrIncompatibleCheckpointFrequency = errors.New("incompatible search checkpoint frequency")
 )
 
 type TruncatedHash [20]byte
@@ -38,6 +68,7 @@ type TruncatedHash [20]byte
 type Metrics interface {
 	RecordEntryCount(count int64)
 	RecordSearchEntriesRead(count int64)
+	RecordTruncatedHashCollision()
 }
 
 type logContext struct {
@@ -57,31 +88,44 @@ type entryStore interface {
 //
 // To keep the append-only format, reduce data size, and support reorg detection and registering of executing-messages:
 //
-// Use a fixed 24 bytes per entry.
+// Use a fixed 28 bytes per entry: 24 bytes of data followed by a 4 byte checksum.
 //
 // Data is an append-only log, that can be binary searched for any necessary event data.
 //
 // Rules:
-// if entry_index % 256 == 0: must be type 0. For easy binary search.
+// if entry_index % checkpoint_frequency == 0: must be type 0. For easy binary search. The frequency defaults to 256.
 // type 1 always adjacent to type 0
-// type 2 "diff" values are offsets from type 0 values (always within 256 entries range)
+// type 2 "diff" values are offsets from type 0 values (always within the checkpoint frequency range)
 // type 3 always after type 2
 // type 4 always after type 3
+// type 5 always before type 2, or before type 0 if the next entry must be a search checkpoint
 //
-// Types (<type> = 1 byte):
+// Types (<type> = 1 byte, <version: high 3 bits><type: low 5 bits>):
 // type 0: "search checkpoint" <type><uint64 block number: 8 bytes><uint32 event index offset: 4 bytes><uint64 timestamp: 8 bytes> = 20 bytes
 // type 1: "canonical hash" <type><parent blockhash truncated: 20 bytes> = 21 bytes
-// type 2: "initiating event" <type><blocknum diff: 1 byte><event flags: 1 byte><event-hash: 20 bytes> = 23 bytes
+// type 2: "initiating event" <type><blocknum diff: 1 byte><event flags: 1 byte><event-hash: 20 bytes><skipped logs: 1 byte> = 24 bytes
 // type 3: "executing link" <type><chain: 4 bytes><blocknum: 8 bytes><event index: 3 bytes><uint64 timestamp: 8 bytes> = 24 bytes
 // type 4: "executing check" <type><event-hash: 20 bytes> = 21 bytes
+// type 5: "skip" <type><uint64 blocknum diff: 8 bytes><uint32 event index: 4 bytes> = 13 bytes
 // other types: future compat. E.g. for linking to L1, registering block-headers as a kind of initiating-event, tracking safe-head progression, etc.
 //
-// Right-pad each entry that is not 24 bytes.
+// Right-pad the data of each entry that is not 24 bytes.
+//
+// version: format version of the entry, so an entry type's layout can change without misreading existing data.
+// Currently 0 for all entries. Each entry is decoded with the layout of its version, entries with a version that
+// isn't supported are rejected rather than guessed at.
+//
+// checksum: <uint32 CRC-32 (IEEE) of the 24 data bytes: 4 bytes>, detects silent corruption of an entry.
 //
 // event-flags: each bit represents a boolean value, currently only two are defined
-// * event-flags & 0x01 - true if the log index should increment. Should only be false when the event is immediately after a search checkpoint and canonical hash
+// * event-flags & 0x01 - true if the log index should increment. Should only be false when the event is immediately after a search checkpoint and canonical hash, or a skip
 // * event-flags & 0x02 - true if the initiating event has an executing link that should follow. Allows detecting when the executing link failed to write.
 // event-hash: H(origin, timestamp, payloadhash); enough to check identifier matches & payload matches.
+// skipped logs: number of log indices to increment by in addition to the one from the event flags. Zero unless logs
+// between initiating events were not recorded.
+//
+// The blocknum diff of an initiating event only fits gaps of up to 255 blocks, and the log index can increment by at
+// most 256. Larger gaps are bridged by a skip entry, which moves to the new log the same way a search checkpoint does.
 type DB struct {
 	log    log.Logger
 	m      Metrics
@@ -89,17 +133,46 @@ type DB struct {
 	rwLock sync.RWMutex
 
 	lastEntryContext logContext
+
+	// checkpointFrequency is the number of entries between search checkpoints
+	checkpointFrequency int64
+
+	// checkCollisions enables tracking the hashes of the logs added to blockLogHashesNum to detect collisions
+	checkCollisions   bool
+	blockLogHashesNum uint64
+	blockLogHashes    map[TruncatedHash]blockLog
+}
+
+// blockLog is a log added to the current block, kept to detect truncated hash collisions
+type blockLog struct {
+	logIdx uint32
+	hash   common.Hash
 }
 
+// NewFromFile opens the database at path, writing a search checkpoint every 256 entries.
 func NewFromFile(logger log.Logger, m Metrics, path string) (*DB, error) {
+	return NewFromFileWithCheckpointFrequency(logger, m, path, searchCheckpointFrequency)
+}
+
+// NewFromFileWithCheckpointFrequency opens the database at path, writing a search checkpoint every frequency entries.
+// Searching relies on finding a search checkpoint every frequency entries, so an existing database must have been
+// written with the same frequency, or a divisor of it.
+func NewFromFileWithCheckpointFrequency(logger log.Logger, m Metrics, path string, frequency int64) (*DB, error) {
+	if frequency < minSearchCheckpointFrequency {
+		return nil, fmt.Errorf("search checkpoint frequency %v is below the minimum of %v", frequency, minSearchCheckpointFrequency)
+	}
 	store, err := entrydb.NewEntryDB(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open DB: %w", err)
 	}
 	db := &DB{
-		log:   logger,
-		m:     m,
-		store: store,
+		log:                 logger,
+		m:                   m,
+		store:               store,
+		checkpointFrequency: frequency,
+	}
+	if err := db.verifyCheckpointFrequency(); err != nil {
+		return nil, fmt.Errorf("failed to verify search checkpoint frequency: %w", err)
 	}
 	if err := db.init(); err != nil {
 		return nil, fmt.Errorf("failed to init database: %w", err)
@@ -107,17 +180,53 @@ func NewFromFile(logger log.Logger, m Metrics, path string) (*DB, error) {
 	return db, nil
 }
 
+// EnableCollisionCheck enables detecting truncated hash collisions between logs added to the same block.
+// A collision is a log with the same truncated hash but a different full hash than a log added earlier in the block.
+// Collisions are logged and recorded in metrics, but the log is still added.
+func (db *DB) EnableCollisionCheck() {
+	db.rwLock.Lock()
+	defer db.rwLock.Unlock()
+	db.checkCollisions = true
+	db.blockLogHashes = make(map[TruncatedHash]blockLog)
+}
+
 func (db *DB) lastEntryIdx() int64 {
 	return db.store.Size() - 1
 }
 
+// verifyCheckpointFrequency checks the existing entries were written with a compatible search checkpoint frequency.
+// Only the last two entries at a multiple of checkpointFrequency are checked. Both are search checkpoints only if
+// checkpointFrequency is a multiple of the frequency the entries were written with, in which case every entry at a
+// multiple of checkpointFrequency is a search checkpoint.
+func (db *DB) verifyCheckpointFrequency() error {
+	if db.lastEntryIdx() < 0 {
+		// Database is empty so any frequency can be used
+		return nil
+	}
+	lastCheckpoint := (db.lastEntryIdx() / db.checkpointFrequency) * db.checkpointFrequency
+	for idx := lastCheckpoint; idx >= 0 && idx > lastCheckpoint-2*db.checkpointFrequency; idx -= db.checkpointFrequency {
+		entry, err := db.store.Read(idx)
+		if err != nil {
+			return fmt.Errorf("failed to read entry %v: %w", idx, err)
+		}
+		if err := verifyChecksum(entry); err != nil {
+			return fmt.Errorf("failed to verify entry %v: %w", idx, err)
+		}
+		if entryType(entry) != typeSearchCheckpoint {
+			return fmt.Errorf("%w: expected search checkpoint every %v entries but entry %v was type %v",
+				ErrIncompatibleCheckpointFrequency, db.checkpointFrequency, idx, entryType(entry))
+		}
+	}
+	return nil
+}
+
 func (db *DB) init() error {
 	db.updateEntryCountMetric()
 	if db.lastEntryIdx() < 0 {
 		// Database is empty so no context to load
 		return nil
 	}
-	lastCheckpoint := (db.lastEntryIdx() / searchCheckpointFrequency) * searchCheckpointFrequency
+	lastCheckpoint := (db.lastEntryIdx() / db.checkpointFrequency) * db.checkpointFrequency
 	i, err := db.newIterator(lastCheckpoint)
 	if err != nil {
 		return fmt.Errorf("failed to create iterator at last search checkpoint: %w", err)
@@ -131,7 +240,7 @@ func (db *DB) init() error {
 			return fmt.Errorf("failed to init from existing entries: %w", err)
 		}
 	}
-	db.lastEntryContext = i.current
+	db.lastEntryContext = i.replay.current
 	return nil
 }
 
@@ -160,25 +269,64 @@ func (db *DB) ClosestBlockInfo(blockNum uint64) (uint64, TruncatedHash, error) {
 	return checkpoint.blockNum, entry.hash, nil
 }
 
+// ClosestSearchCheckpoint returns the index of the last search checkpoint entry at or before the specified blockNum and
+// logIdx, from which entries can be read to find the log.
+// Returns ErrNotFound if there is no search checkpoint at or before the log.
+func (db *DB) ClosestSearchCheckpoint(blockNum uint64, logIdx uint32) (int64, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	entryIdx, err := db.searchCheckpoint(blockNum, logIdx)
+	if errors.Is(err, io.EOF) {
+		return 0, ErrNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	return entryIdx, nil
+}
+
 // Contains return true iff the specified logHash is recorded in the specified blockNum and logIdx.
 // logIdx is the index of the log in the array of all logs the block.
 func (db *DB) Contains(blockNum uint64, logIdx uint32, logHash TruncatedHash) (bool, error) {
 	db.rwLock.RLock()
 	defer db.rwLock.RUnlock()
 	db.log.Trace("Checking for log", "blockNum", blockNum, "logIdx", logIdx, "hash", logHash)
+	_, evtHash, err := db.findLogEntry(blockNum, logIdx)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	// Found the requested block and log index, check if the hash matches
+	return evtHash == logHash, nil
+}
+
+// FindLogEntry returns the index of the initiating event entry for the specified blockNum and logIdx.
+// logIdx is the index of the log in the array of all logs the block.
+// Returns ErrNotFound if no log is recorded at the specified blockNum and logIdx.
+func (db *DB) FindLogEntry(blockNum uint64, logIdx uint32) (int64, error) {
+	db.rwLock.RLock()
+	defer db.rwLock.RUnlock()
+	entryIdx, _, err := db.findLogEntry(blockNum, logIdx)
+	return entryIdx, err
+}
+
+// findLogEntry returns the index and hash of the initiating event entry for the specified blockNum and logIdx.
+// The closest search checkpoint is used as starting point, from which entries are scanned forward.
+// Returns ErrNotFound if no log is recorded at the specified blockNum and logIdx.
+func (db *DB) findLogEntry(blockNum uint64, logIdx uint32) (int64, TruncatedHash, error) {
 	entryIdx, err := db.searchCheckpoint(blockNum, logIdx)
 	if errors.Is(err, io.EOF) {
 		// Did not find a checkpoint to start reading from so the log cannot be present.
-		return false, nil
+		return 0, TruncatedHash{}, ErrNotFound
 	} else if err != nil {
-		return false, err
+		return 0, TruncatedHash{}, err
 	}
 
 	i, err := db.newIterator(entryIdx)
 	if err != nil {
-		return false, fmt.Errorf("failed to create iterator: %w", err)
+		return 0, TruncatedHash{}, fmt.Errorf("failed to create iterator: %w", err)
 	}
-	db.log.Trace("Starting search", "entry", entryIdx, "blockNum", i.current.blockNum, "logIdx", i.current.logIdx)
+	db.log.Trace("Starting search", "entry", entryIdx, "blockNum", i.replay.current.blockNum, "logIdx", i.replay.current.logIdx)
 	defer func() {
 		db.m.RecordSearchEntriesRead(i.entriesRead)
 	}()
@@ -186,18 +334,18 @@ func (db *DB) Contains(blockNum uint64, logIdx uint32, logHash TruncatedHash) (b
 		evtBlockNum, evtLogIdx, evtHash, err := i.NextLog()
 		if errors.Is(err, io.EOF) {
 			// Reached end of log without finding the event
-			return false, nil
+			return 0, TruncatedHash{}, ErrNotFound
 		} else if err != nil {
-			return false, fmt.Errorf("failed to read next log: %w", err)
+			return 0, TruncatedHash{}, fmt.Errorf("failed to read next log: %w", err)
 		}
 		if evtBlockNum == blockNum && evtLogIdx == logIdx {
 			db.log.Trace("Found initiatingEvent", "blockNum", evtBlockNum, "logIdx", evtLogIdx, "hash", evtHash)
-			// Found the requested block and log index, check if the hash matches
-			return evtHash == logHash, nil
+			// The iterator has moved past the entry of the returned log
+			return i.nextEntryIdx - 1, evtHash, nil
 		}
 		if evtBlockNum > blockNum || (evtBlockNum == blockNum && evtLogIdx > logIdx) {
 			// Progressed past the requested log without finding it.
-			return false, nil
+			return 0, TruncatedHash{}, ErrNotFound
 		}
 	}
 }
@@ -213,10 +361,7 @@ func (db *DB) newIterator(startCheckpointEntry int64) (*iterator, error) {
 		db: db,
 		// +2 to skip the initial search checkpoint and the canonical hash event after it
 		nextEntryIdx: startCheckpointEntry + 2,
-		current: logContext{
-			blockNum: current.blockNum,
-			logIdx:   current.logIdx,
-		},
+		replay:       newReplayer(current),
 	}
 	return i, nil
 }
@@ -225,13 +370,13 @@ func (db *DB) newIterator(startCheckpointEntry int64) (*iterator, error) {
 // the requested log.
 // Returns the index of the searchCheckpoint to begin reading from or an error
 func (db *DB) searchCheckpoint(blockNum uint64, logIdx uint32) (int64, error) {
-	n := (db.lastEntryIdx() / searchCheckpointFrequency) + 1
+	n := (db.lastEntryIdx() / db.checkpointFrequency) + 1
 	// Define x[-1] < target and x[n] >= target.
 	// Invariant: x[i-1] < target, x[j] >= target.
 	i, j := int64(0), n
 	for i < j {
 		h := int64(uint64(i+j) >> 1) // avoid overflow when computing h
-		checkpoint, err := db.readSearchCheckpoint(h * searchCheckpointFrequency)
+		checkpoint, err := db.readSearchCheckpoint(h * db.checkpointFrequency)
 		if err != nil {
 			return 0, fmt.Errorf("failed to read entry %v: %w", h, err)
 		}
@@ -243,13 +388,13 @@ func (db *DB) searchCheckpoint(blockNum uint64, logIdx uint32) (int64, error) {
 		}
 	}
 	if i < n {
-		checkpoint, err := db.readSearchCheckpoint(i * searchCheckpointFrequency)
+		checkpoint, err := db.readSearchCheckpoint(i * db.checkpointFrequency)
 		if err != nil {
 			return 0, fmt.Errorf("failed to read entry %v: %w", i, err)
 		}
 		if checkpoint.blockNum == blockNum && checkpoint.logIdx == logIdx {
 			// Found entry at requested block number and log index
-			return i * searchCheckpointFrequency, nil
+			return i * db.checkpointFrequency, nil
 		}
 	}
 	if i == 0 {
@@ -257,10 +402,10 @@ func (db *DB) searchCheckpoint(blockNum uint64, logIdx uint32) (int64, error) {
 		return 0, io.EOF
 	}
 	// Not found, need to start reading from the entry prior
-	return (i - 1) * searchCheckpointFrequency, nil
+	return (i - 1) * db.checkpointFrequency, nil
 }
 
-func (db *DB) AddLog(logHash TruncatedHash, block eth.BlockID, timestamp uint64, logIdx uint32) error {
+func (db *DB) AddLog(logHash common.Hash, block eth.BlockID, timestamp uint64, logIdx uint32) error {
 	db.rwLock.Lock()
 	defer db.rwLock.Unlock()
 	postState := logContext{
@@ -273,24 +418,31 @@ func (db *DB) AddLog(logHash TruncatedHash, block eth.BlockID, timestamp uint64,
 	if db.lastEntryContext.blockNum > block.Number {
 		return fmt.Errorf("%w: adding block %v, head block: %v", ErrLogOutOfOrder, block.Number, db.lastEntryContext.blockNum)
 	}
-	if db.lastEntryContext.blockNum == block.Number && db.lastEntryContext.logIdx+1 != logIdx {
+	if db.lastEntryContext.blockNum == block.Number && db.lastEntryContext.logIdx >= logIdx {
 		return fmt.Errorf("%w: adding log %v in block %v, but currently at log %v", ErrLogOutOfOrder, logIdx, block.Number, db.lastEntryContext.logIdx)
 	}
-	if db.lastEntryContext.blockNum < block.Number && logIdx != 0 {
-		return fmt.Errorf("%w: adding log %v as first log in block %v", ErrLogOutOfOrder, logIdx, block.Number)
+	if (db.lastEntryIdx()+1)%db.checkpointFrequency != 0 && requiresSkip(db.lastEntryContext, block.Number, logIdx) {
+		// Too many blocks or logs to record in the initiating event, so skip ahead first.
+		// Not needed if a search checkpoint is about to be written as that will reset the context anyway.
+		if err := db.writeSkip(block.Number, logIdx); err != nil {
+			return fmt.Errorf("failed to write skip: %w", err)
+		}
 	}
-	if (db.lastEntryIdx()+1)%searchCheckpointFrequency == 0 {
+	if (db.lastEntryIdx()+1)%db.checkpointFrequency == 0 {
 		if err := db.writeSearchCheckpoint(block.Number, logIdx, timestamp, block.Hash); err != nil {
 			return fmt.Errorf("failed to write search checkpoint: %w", err)
 		}
 		db.lastEntryContext = postState
 	}
 
-	if err := db.writeInitiatingEvent(postState, logHash); err != nil {
+	if err := db.writeInitiatingEvent(postState, TruncateHash(logHash)); err != nil {
 		return err
 	}
 	db.lastEntryContext = postState
 	db.updateEntryCountMetric()
+	if db.checkCollisions {
+		db.checkCollision(logHash, block.Number, logIdx)
+	}
 	return nil
 }
 
@@ -344,6 +496,9 @@ func (db *DB) Rewind(headBlockNum uint64) error {
 	if err := db.init(); err != nil {
 		return fmt.Errorf("failed to find new last entry context: %w", err)
 	}
+	// The tracked logs may have been removed, and only truncated hashes are stored so they can't be reloaded
+	clear(db.blockLogHashes)
+	db.blockLogHashesNum = 0
 	return nil
 }
 
@@ -377,14 +532,14 @@ func (db *DB) readCanonicalHash(entryIdx int64) (canonicalHash, error) {
 	if err != nil {
 		return canonicalHash{}, fmt.Errorf("failed to read entry %v: %w", entryIdx, err)
 	}
-	if data[0] != typeCanonicalHash {
-		return canonicalHash{}, fmt.Errorf("%w: expected canonical hash at entry %v but was type %v", ErrDataCorruption, entryIdx, data[0])
+	if entryType(data) != typeCanonicalHash {
+		return canonicalHash{}, fmt.Errorf("%w: expected canonical hash at entry %v but was type %v", ErrDataCorruption, entryIdx, entryType(data))
 	}
 	return newCanonicalHashFromEntry(data)
 }
 
 // writeInitiatingEvent appends an initiating event to the log
-// type 2: "initiating event" <type><blocknum diff: 1 byte><event flags: 1 byte><event-hash: 20 bytes> = 23 bytes
+// type 2: "initiating event" <type><blocknum diff: 1 byte><event flags: 1 byte><event-hash: 20 bytes><skipped logs: 1 byte> = 24 bytes
 func (db *DB) writeInitiatingEvent(postState logContext, logHash TruncatedHash) error {
 	evt, err := newInitiatingEvent(db.lastEntryContext, postState.blockNum, postState.logIdx, logHash)
 	if err != nil {
@@ -393,6 +548,32 @@ func (db *DB) writeInitiatingEvent(postState logContext, logHash TruncatedHash)
 	return db.store.Append(evt.encode())
 }
 
+// checkCollision reports if a different log with the same truncated hash as logHash was already added to the same
+// block and records the log for later checks
+func (db *DB) checkCollision(logHash common.Hash, blockNum uint64, logIdx uint32) {
+	if db.blockLogHashesNum != blockNum {
+		clear(db.blockLogHashes)
+		db.blockLogHashesNum = blockNum
+	}
+	truncated := TruncateHash(logHash)
+	if prev, ok := db.blockLogHashes[truncated]; ok && prev.hash != logHash {
+		db.log.Warn("Truncated log hash collision", "blockNum", blockNum, "logIdx", logIdx, "hash", logHash, "prevLogIdx", prev.logIdx, "prevHash", prev.hash)
+		db.m.RecordTruncatedHashCollision()
+	}
+	db.blockLogHashes[truncated] = blockLog{logIdx: logIdx, hash: logHash}
+}
+
+// writeSkip appends a skip entry to the log, moving the last entry context to logIdx in blockNum
+// type 5: "skip" <type><uint64 blocknum diff: 8 bytes><uint32 event index: 4 bytes> = 13 bytes
+func (db *DB) writeSkip(blockNum uint64, logIdx uint32) error {
+	s := newSkip(db.lastEntryContext, blockNum, logIdx)
+	if err := db.store.Append(s.encode()); err != nil {
+		return err
+	}
+	db.lastEntryContext = s.postContext(db.lastEntryContext)
+	return nil
+}
+
 func TruncateHash(hash common.Hash) TruncatedHash {
 	var truncated TruncatedHash
 	copy(truncated[:], hash[0:20])
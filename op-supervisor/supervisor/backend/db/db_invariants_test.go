@@ -14,7 +14,8 @@ type statInvariant func(stat os.FileInfo, m *stubMetrics) error
 type entryInvariant func(entryIdx int, entry entrydb.Entry, entries []entrydb.Entry, m *stubMetrics) error
 
 // checkDBInvariants reads the database log directly and asserts a set of invariants on the data.
-func checkDBInvariants(t *testing.T, dbPath string, m *stubMetrics) {
+// checkpointFrequency is the search checkpoint frequency the database was written with.
+func checkDBInvariants(t *testing.T, dbPath string, checkpointFrequency int, m *stubMetrics) {
 	stat, err := os.Stat(dbPath)
 	require.NoError(t, err)
 
@@ -37,11 +38,12 @@ func checkDBInvariants(t *testing.T, dbPath string, m *stubMetrics) {
 	}
 
 	entryInvariants := []entryInvariant{
-		invariantSearchCheckpointOnlyAtFrequency,
-		invariantSearchCheckpointAtEverySearchCheckpointFrequency,
+		invariantSearchCheckpointOnlyAtFrequency(checkpointFrequency),
+		invariantSearchCheckpointAtEverySearchCheckpointFrequency(checkpointFrequency),
 		invariantCanonicalHashAfterEverySearchCheckpoint,
 		invariantSearchCheckpointBeforeEveryCanonicalHash,
 		invariantIncrementLogIdxIfNotImmediatelyAfterCanonicalHash,
+		invariantSkipFollowedByInitiatingEventOrSearchCheckpoint,
 	}
 	for i, entry := range entries {
 		for _, invariant := range entryInvariants {
@@ -77,32 +79,36 @@ func invariantFileSizeMatchesEntryCountMetric(stat os.FileInfo, m *stubMetrics)
 	return nil
 }
 
-func invariantSearchCheckpointOnlyAtFrequency(entryIdx int, entry entrydb.Entry, entries []entrydb.Entry, m *stubMetrics) error {
-	if entry[0] != typeSearchCheckpoint {
+func invariantSearchCheckpointOnlyAtFrequency(frequency int) entryInvariant {
+	return func(entryIdx int, entry entrydb.Entry, entries []entrydb.Entry, m *stubMetrics) error {
+		if entryType(entry) != typeSearchCheckpoint {
+			return nil
+		}
+		if entryIdx%frequency != 0 {
+			return fmt.Errorf("should only have search checkpoints every %v entries but found at entry %v", frequency, entryIdx)
+		}
 		return nil
 	}
-	if entryIdx%searchCheckpointFrequency != 0 {
-		return fmt.Errorf("should only have search checkpoints every %v entries but found at entry %v", searchCheckpointFrequency, entryIdx)
-	}
-	return nil
 }
 
-func invariantSearchCheckpointAtEverySearchCheckpointFrequency(entryIdx int, entry entrydb.Entry, entries []entrydb.Entry, m *stubMetrics) error {
-	if entryIdx%searchCheckpointFrequency == 0 && entry[0] != typeSearchCheckpoint {
-		return fmt.Errorf("should have search checkpoints every %v entries but entry %v was %x", searchCheckpointFrequency, entryIdx, entry)
+func invariantSearchCheckpointAtEverySearchCheckpointFrequency(frequency int) entryInvariant {
+	return func(entryIdx int, entry entrydb.Entry, entries []entrydb.Entry, m *stubMetrics) error {
+		if entryIdx%frequency == 0 && entryType(entry) != typeSearchCheckpoint {
+			return fmt.Errorf("should have search checkpoints every %v entries but entry %v was %x", frequency, entryIdx, entry)
+		}
+		return nil
 	}
-	return nil
 }
 
 func invariantCanonicalHashAfterEverySearchCheckpoint(entryIdx int, entry entrydb.Entry, entries []entrydb.Entry, m *stubMetrics) error {
-	if entry[0] != typeSearchCheckpoint {
+	if entryType(entry) != typeSearchCheckpoint {
 		return nil
 	}
 	if entryIdx+1 >= len(entries) {
 		return fmt.Errorf("expected canonical hash after search checkpoint at entry %v but no further entries found", entryIdx)
 	}
 	nextEntry := entries[entryIdx+1]
-	if nextEntry[0] != typeCanonicalHash {
+	if entryType(nextEntry) != typeCanonicalHash {
 		return fmt.Errorf("expected canonical hash after search checkpoint at entry %v but got %x", entryIdx, nextEntry)
 	}
 	return nil
@@ -110,21 +116,21 @@ func invariantCanonicalHashAfterEverySearchCheckpoint(entryIdx int, entry entryd
 
 // invariantSearchCheckpointBeforeEveryCanonicalHash ensures we don't have extra canonical-hash entries
 func invariantSearchCheckpointBeforeEveryCanonicalHash(entryIdx int, entry entrydb.Entry, entries []entrydb.Entry, m *stubMetrics) error {
-	if entry[0] != typeCanonicalHash {
+	if entryType(entry) != typeCanonicalHash {
 		return nil
 	}
 	if entryIdx == 0 {
 		return fmt.Errorf("expected search checkpoint before canonical hash at entry %v but no previous entries present", entryIdx)
 	}
 	prevEntry := entries[entryIdx-1]
-	if prevEntry[0] != typeSearchCheckpoint {
+	if entryType(prevEntry) != typeSearchCheckpoint {
 		return fmt.Errorf("expected search checkpoint before canonical hash at entry %v but got %x", entryIdx, prevEntry)
 	}
 	return nil
 }
 
 func invariantIncrementLogIdxIfNotImmediatelyAfterCanonicalHash(entryIdx int, entry entrydb.Entry, entries []entrydb.Entry, m *stubMetrics) error {
-	if entry[0] != typeInitiatingEvent {
+	if entryType(entry) != typeInitiatingEvent {
 		return nil
 	}
 	if entryIdx == 0 {
@@ -134,15 +140,33 @@ func invariantIncrementLogIdxIfNotImmediatelyAfterCanonicalHash(entryIdx int, en
 	flags := entry[2]
 	incrementsLogIdx := flags&eventFlagIncrementLogIdx != 0
 	prevEntry := entries[entryIdx-1]
-	prevEntryIsCanonicalHash := prevEntry[0] == typeCanonicalHash
+	prevEntryIsCanonicalHash := entryType(prevEntry) == typeCanonicalHash
+	prevEntryIsSkip := entryType(prevEntry) == typeSkip
 	if incrementsLogIdx && prevEntryIsCanonicalHash {
 		return fmt.Errorf("initiating event at index %v increments logIdx despite being immediately after canonical hash (prev entry %x)", entryIdx, prevEntry)
 	}
-	if incrementsLogIdx && blockDiff > 0 {
-		return fmt.Errorf("initiating event at index %v increments logIdx despite starting a new block", entryIdx)
+	if incrementsLogIdx && prevEntryIsSkip {
+		return fmt.Errorf("initiating event at index %v increments logIdx despite being immediately after skip (prev entry %x)", entryIdx, prevEntry)
 	}
-	if !incrementsLogIdx && !prevEntryIsCanonicalHash && blockDiff == 0 {
+	if blockDiff > 0 && prevEntryIsSkip {
+		return fmt.Errorf("initiating event at index %v has block diff despite being immediately after skip (prev entry %x)", entryIdx, prevEntry)
+	}
+	if !incrementsLogIdx && !prevEntryIsCanonicalHash && !prevEntryIsSkip && blockDiff == 0 {
 		return fmt.Errorf("initiating event at index %v does not increment logIdx when block unchanged and not after canonical hash (prev entry %x)", entryIdx, prevEntry)
 	}
 	return nil
 }
+
+func invariantSkipFollowedByInitiatingEventOrSearchCheckpoint(entryIdx int, entry entrydb.Entry, entries []entrydb.Entry, m *stubMetrics) error {
+	if entryType(entry) != typeSkip {
+		return nil
+	}
+	if entryIdx+1 >= len(entries) {
+		return fmt.Errorf("expected initiating event after skip at entry %v but no further entries found", entryIdx)
+	}
+	nextEntry := entries[entryIdx+1]
+	if entryType(nextEntry) != typeInitiatingEvent && entryType(nextEntry) != typeSearchCheckpoint {
+		return fmt.Errorf("expected initiating event or search checkpoint after skip at entry %v but got %x", entryIdx, nextEntry)
+	}
+	return nil
+}
@@ -2,14 +2,18 @@ package db
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/ethereum-optimism/optimism/op-service/eth"
 	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/stretchr/testify/require"
@@ -31,11 +35,15 @@ func TestErrorOpeningDatabase(t *testing.T) {
 }
 
 func runDBTest(t *testing.T, setup func(t *testing.T, db *DB, m *stubMetrics), assert func(t *testing.T, db *DB, m *stubMetrics)) {
+	runDBTestWithCheckpointFrequency(t, searchCheckpointFrequency, setup, assert)
+}
+
+func runDBTestWithCheckpointFrequency(t *testing.T, frequency int64, setup func(t *testing.T, db *DB, m *stubMetrics), assert func(t *testing.T, db *DB, m *stubMetrics)) {
 	createDb := func(t *testing.T, dir string) (*DB, *stubMetrics, string) {
 		logger := testlog.Logger(t, log.LvlTrace)
 		path := filepath.Join(dir, "test.db")
 		m := &stubMetrics{}
-		db, err := NewFromFile(logger, m, path)
+		db, err := NewFromFileWithCheckpointFrequency(logger, m, path, frequency)
 		require.NoError(t, err, "Failed to create database")
 		t.Cleanup(func() {
 			err := db.Close()
@@ -58,11 +66,11 @@ func runDBTest(t *testing.T, setup func(t *testing.T, db *DB, m *stubMetrics), a
 		setup(t, db, m)
 		// Close and recreate the database
 		require.NoError(t, db.Close())
-		checkDBInvariants(t, path, m)
+		checkDBInvariants(t, path, int(frequency), m)
 
 		db2, m, path := createDb(t, dir)
 		assert(t, db2, m)
-		checkDBInvariants(t, path, m)
+		checkDBInvariants(t, path, int(frequency), m)
 	})
 }
 
@@ -81,7 +89,7 @@ func TestAddLog(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {},
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 0}, 5000, 0)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 0}, 5000, 0)
 				require.ErrorIs(t, err, ErrLogOutOfOrder)
 			})
 	})
@@ -89,7 +97,7 @@ func TestAddLog(t *testing.T) {
 	t.Run("FirstEntry", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
 				require.NoError(t, err)
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -100,11 +108,11 @@ func TestAddLog(t *testing.T) {
 	t.Run("MultipleEntriesFromSameBlock", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
 				require.NoError(t, err)
-				err = db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1)
+				err = db.AddLog(createHash(2), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1)
 				require.NoError(t, err)
-				err = db.AddLog(createTruncatedHash(3), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 2)
+				err = db.AddLog(createHash(3), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 2)
 				require.NoError(t, err)
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -118,13 +126,13 @@ func TestAddLog(t *testing.T) {
 	t.Run("MultipleEntriesFromMultipleBlocks", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
 				require.NoError(t, err)
-				err = db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1)
+				err = db.AddLog(createHash(2), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1)
 				require.NoError(t, err)
-				err = db.AddLog(createTruncatedHash(3), eth.BlockID{Hash: createHash(16), Number: 16}, 5002, 0)
+				err = db.AddLog(createHash(3), eth.BlockID{Hash: createHash(16), Number: 16}, 5002, 0)
 				require.NoError(t, err)
-				err = db.AddLog(createTruncatedHash(4), eth.BlockID{Hash: createHash(16), Number: 16}, 5002, 1)
+				err = db.AddLog(createHash(4), eth.BlockID{Hash: createHash(16), Number: 16}, 5002, 1)
 				require.NoError(t, err)
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -136,14 +144,73 @@ func TestAddLog(t *testing.T) {
 			})
 	})
 
+	t.Run("MaxBlockDiffWithoutSkip", func(t *testing.T) {
+		runDBTest(t,
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
+				require.NoError(t, err)
+				err = db.AddLog(createHash(2), eth.BlockID{Hash: createHash(15 + math.MaxUint8), Number: 15 + math.MaxUint8}, 5002, 0)
+				require.NoError(t, err)
+			},
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				require.EqualValues(t, 4, m.entryCount, "should not write skip when block diff fits in initiating event")
+				requireContains(t, db, 15, 0, createHash(1))
+				requireContains(t, db, 15+math.MaxUint8, 0, createHash(2))
+			})
+	})
+
+	for _, gap := range []uint64{256, 1000, 70000} {
+		gap := gap
+		t.Run(fmt.Sprintf("LargeBlockGap-%v", gap), func(t *testing.T) {
+			block1 := eth.BlockID{Hash: createHash(15), Number: 15}
+			block2 := eth.BlockID{Hash: createHash(16), Number: 15 + gap}
+			runDBTest(t,
+				func(t *testing.T, db *DB, m *stubMetrics) {
+					require.NoError(t, db.AddLog(createHash(1), block1, 5000, 0))
+					require.NoError(t, db.AddLog(createHash(2), block2, 5002, 0))
+					require.NoError(t, db.AddLog(createHash(3), block2, 5002, 1))
+				},
+				func(t *testing.T, db *DB, m *stubMetrics) {
+					require.EqualValues(t, 6, m.entryCount, "should write a single skip entry")
+					requireContains(t, db, block1.Number, 0, createHash(1))
+					requireContains(t, db, block2.Number, 0, createHash(2))
+					requireContains(t, db, block2.Number, 1, createHash(3))
+					requireNotContains(t, db, block1.Number+math.MaxUint8, 0, createHash(2))
+					requireNotContains(t, db, block2.Number-1, 0, createHash(2))
+				})
+		})
+	}
+
+	t.Run("LargeBlockGapAtSearchCheckpoint", func(t *testing.T) {
+		block1 := eth.BlockID{Hash: createHash(15), Number: 15}
+		block2 := eth.BlockID{Hash: createHash(16), Number: 15 + 1000}
+		// Fill up to the entry immediately before the second search checkpoint
+		block1LogCount := searchCheckpointFrequency - 3
+		runDBTest(t,
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				for i := 0; i < block1LogCount; i++ {
+					err := db.AddLog(createHash(i), block1, 5000, uint32(i))
+					require.NoErrorf(t, err, "failed to add log %v of block 1", i)
+				}
+				require.EqualValues(t, searchCheckpointFrequency-1, m.entryCount)
+				require.NoError(t, db.AddLog(createHash(1), block2, 5002, 0))
+			},
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				// Skip, search checkpoint, canonical hash and initiating event
+				require.EqualValues(t, searchCheckpointFrequency+3, m.entryCount)
+				requireContains(t, db, block1.Number, uint32(block1LogCount-1), createHash(block1LogCount-1))
+				requireContains(t, db, block2.Number, 0, createHash(1))
+			})
+	})
+
 	t.Run("ErrorWhenBeforeCurrentBlock", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
 				require.NoError(t, err)
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(14), Number: 14}, 4998, 0)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(14), Number: 14}, 4998, 0)
 				require.ErrorIs(t, err, ErrLogOutOfOrder)
 			})
 	})
@@ -151,13 +218,13 @@ func TestAddLog(t *testing.T) {
 	t.Run("ErrorWhenBeforeCurrentBlockButAfterLastCheckpoint", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(13), Number: 13}, 5000, 0)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(13), Number: 13}, 5000, 0)
 				require.NoError(t, err)
-				err = db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
+				err = db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
 				require.NoError(t, err)
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(14), Number: 14}, 4998, 0)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(14), Number: 14}, 4998, 0)
 				require.ErrorIs(t, err, ErrLogOutOfOrder)
 			})
 	})
@@ -165,11 +232,11 @@ func TestAddLog(t *testing.T) {
 	t.Run("ErrorWhenBeforeCurrentLogEvent", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(14), Number: 15}, 4998, 0)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(14), Number: 15}, 4998, 0)
 				require.ErrorIs(t, err, ErrLogOutOfOrder)
 			})
 	})
@@ -177,15 +244,15 @@ func TestAddLog(t *testing.T) {
 	t.Run("ErrorWhenBeforeCurrentLogEventButAfterLastCheckpoint", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
 				require.NoError(t, err)
-				err = db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1)
+				err = db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1)
 				require.NoError(t, err)
-				err = db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 2)
+				err = db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 2)
 				require.NoError(t, err)
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(14), Number: 15}, 4998, 1)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(14), Number: 15}, 4998, 1)
 				require.ErrorIs(t, err, ErrLogOutOfOrder)
 			})
 	})
@@ -193,11 +260,11 @@ func TestAddLog(t *testing.T) {
 	t.Run("ErrorWhenAtCurrentLogEvent", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 4998, 1)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 4998, 1)
 				require.ErrorIs(t, err, ErrLogOutOfOrder)
 			})
 	})
@@ -205,44 +272,97 @@ func TestAddLog(t *testing.T) {
 	t.Run("ErrorWhenAtCurrentLogEventButAfterLastCheckpoint", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 2))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 2))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(14), Number: 15}, 4998, 2)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(14), Number: 15}, 4998, 2)
 				require.ErrorIs(t, err, ErrLogOutOfOrder)
 			})
 	})
 
-	t.Run("ErrorWhenSkippingLogEvent", func(t *testing.T) {
+	t.Run("SkippedLogEvents", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0)
-				require.NoError(t, err)
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 2))
+				require.NoError(t, db.AddLog(createHash(3), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 7))
+				require.NoError(t, db.AddLog(createHash(4), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 8))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 4998, 2)
-				require.ErrorIs(t, err, ErrLogOutOfOrder)
+				require.EqualValues(t, 6, m.entryCount, "should record skipped logs in the initiating events")
+				requireContains(t, db, 15, 0, createHash(1))
+				requireContains(t, db, 15, 2, createHash(2))
+				requireContains(t, db, 15, 7, createHash(3))
+				requireContains(t, db, 15, 8, createHash(4))
+				requireNotContains(t, db, 15, 1, createHash(2))
+				requireNotContains(t, db, 15, 5, createHash(3))
 			})
 	})
 
-	t.Run("ErrorWhenFirstLogIsNotLogIdxZero", func(t *testing.T) {
-		runDBTest(t, func(t *testing.T, db *DB, m *stubMetrics) {},
+	t.Run("FirstLogIsNotLogIdxZero", func(t *testing.T) {
+		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 4998, 5)
-				require.ErrorIs(t, err, ErrLogOutOfOrder)
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 4998, 5))
+			},
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				requireContains(t, db, 15, 5, createHash(1))
+				requireNotContains(t, db, 15, 0, createHash(1))
 			})
 	})
 
-	t.Run("ErrorWhenFirstLogOfNewBlockIsNotLogIdxZero", func(t *testing.T) {
+	t.Run("FirstLogOfNewBlockIsNotLogIdxZero", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(14), Number: 14}, 4996, 0))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(14), Number: 14}, 4996, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(15), Number: 15}, 4998, 3))
+				require.NoError(t, db.AddLog(createHash(3), eth.BlockID{Hash: createHash(15), Number: 15}, 4998, 4))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 4998, 1)
-				require.ErrorIs(t, err, ErrLogOutOfOrder)
+				require.EqualValues(t, 5, m.entryCount)
+				requireContains(t, db, 14, 0, createHash(1))
+				requireContains(t, db, 15, 3, createHash(2))
+				requireContains(t, db, 15, 4, createHash(3))
+				requireNotContains(t, db, 15, 0, createHash(2))
+			})
+	})
+
+	for _, logGap := range []uint32{2, 5, maxInitiatingEventLogDiff, maxInitiatingEventLogDiff + 1, 70000} {
+		logGap := logGap
+		t.Run(fmt.Sprintf("LogGap-%v", logGap), func(t *testing.T) {
+			block := eth.BlockID{Hash: createHash(15), Number: 15}
+			expectedEntryCount := 4
+			if logGap > maxInitiatingEventLogDiff {
+				expectedEntryCount++
+			}
+			runDBTest(t,
+				func(t *testing.T, db *DB, m *stubMetrics) {
+					require.NoError(t, db.AddLog(createHash(1), block, 5000, 0))
+					require.NoError(t, db.AddLog(createHash(2), block, 5000, logGap))
+				},
+				func(t *testing.T, db *DB, m *stubMetrics) {
+					require.EqualValues(t, expectedEntryCount, m.entryCount, "should only write skip when log gap overflows initiating event")
+					requireContains(t, db, block.Number, 0, createHash(1))
+					requireContains(t, db, block.Number, logGap, createHash(2))
+					requireNotContains(t, db, block.Number, logGap-1, createHash(2))
+					// Should be able to continue adding logs after the gap
+					require.NoError(t, db.AddLog(createHash(3), block, 5000, logGap+1))
+					requireContains(t, db, block.Number, logGap+1, createHash(3))
+				})
+		})
+	}
+
+	t.Run("LogGapInNewBlockOverflowsInitiatingEvent", func(t *testing.T) {
+		runDBTest(t,
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(14), Number: 14}, 4996, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(15), Number: 15}, 4998, 1000))
+			},
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				require.EqualValues(t, 5, m.entryCount)
+				requireContains(t, db, 14, 0, createHash(1))
+				requireContains(t, db, 15, 1000, createHash(2))
 			})
 	})
 
@@ -262,15 +382,15 @@ func TestAddLog(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
 				for i := 0; i < block1LogCount; i++ {
-					err := db.AddLog(createTruncatedHash(i), block1, 3000, uint32(i))
+					err := db.AddLog(createHash(i), block1, 3000, uint32(i))
 					require.NoErrorf(t, err, "failed to add log %v of block 1", i)
 				}
 				for i := 0; i < block2LogCount; i++ {
-					err := db.AddLog(createTruncatedHash(i), block2, 3002, uint32(i))
+					err := db.AddLog(createHash(i), block2, 3002, uint32(i))
 					require.NoErrorf(t, err, "failed to add log %v of block 2", i)
 				}
 				for i := 0; i < block3LogCount; i++ {
-					err := db.AddLog(createTruncatedHash(i), block3, 3004, uint32(i))
+					err := db.AddLog(createHash(i), block3, 3004, uint32(i))
 					require.NoErrorf(t, err, "failed to add log %v of block 3", i)
 				}
 				// Verify that we're right before the fourth checkpoint will be written.
@@ -279,7 +399,7 @@ func TestAddLog(t *testing.T) {
 				// so the fourth is at entry 3*searchCheckpointFrequency
 				require.EqualValues(t, 3*searchCheckpointFrequency, m.entryCount)
 				for i := 0; i < block4LogCount; i++ {
-					err := db.AddLog(createTruncatedHash(i), block4, 3006, uint32(i))
+					err := db.AddLog(createHash(i), block4, 3006, uint32(i))
 					require.NoErrorf(t, err, "failed to add log %v of block 4", i)
 				}
 			},
@@ -311,11 +431,11 @@ func TestAddLog(t *testing.T) {
 func TestContains(t *testing.T) {
 	runDBTest(t,
 		func(t *testing.T, db *DB, m *stubMetrics) {
-			require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
-			require.NoError(t, db.AddLog(createTruncatedHash(3), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
-			require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 2))
-			require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(52), Number: 52}, 500, 0))
-			require.NoError(t, db.AddLog(createTruncatedHash(3), eth.BlockID{Hash: createHash(52), Number: 52}, 500, 1))
+			require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
+			require.NoError(t, db.AddLog(createHash(3), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
+			require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 2))
+			require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(52), Number: 52}, 500, 0))
+			require.NoError(t, db.AddLog(createHash(3), eth.BlockID{Hash: createHash(52), Number: 52}, 500, 1))
 		},
 		func(t *testing.T, db *DB, m *stubMetrics) {
 			// Should find added logs
@@ -343,6 +463,60 @@ func TestContains(t *testing.T) {
 		})
 }
 
+func TestFindLogEntry(t *testing.T) {
+	block1 := eth.BlockID{Hash: createHash(50), Number: 50}
+	block2 := eth.BlockID{Hash: createHash(52), Number: 52}
+	block3 := eth.BlockID{Hash: createHash(53), Number: 53}
+	// Block 3 logs extend past the second search checkpoint
+	block3LogCount := searchCheckpointFrequency
+	runDBTest(t,
+		func(t *testing.T, db *DB, m *stubMetrics) {
+			require.NoError(t, db.AddLog(createHash(1), block1, 500, 0))
+			require.NoError(t, db.AddLog(createHash(2), block1, 500, 1))
+			require.NoError(t, db.AddLog(createHash(3), block1, 500, 2))
+			require.NoError(t, db.AddLog(createHash(4), block2, 504, 0))
+			require.NoError(t, db.AddLog(createHash(5), block2, 504, 3))
+			for i := 0; i < block3LogCount; i++ {
+				require.NoError(t, db.AddLog(createHash(i), block3, 506, uint32(i)))
+			}
+		},
+		func(t *testing.T, db *DB, m *stubMetrics) {
+			requireFindLogEntry := func(blockNum uint64, logIdx uint32, expectedIdx int64, expectedHash common.Hash) {
+				idx, err := db.FindLogEntry(blockNum, logIdx)
+				require.NoError(t, err)
+				require.Equal(t, expectedIdx, idx)
+				entry, err := db.store.Read(idx)
+				require.NoError(t, err)
+				evt, err := newInitiatingEventFromEntry(entry)
+				require.NoError(t, err)
+				require.Equal(t, TruncateHash(expectedHash), evt.logHash)
+			}
+			// Entries 0 and 1 are the first search checkpoint and canonical hash
+			requireFindLogEntry(block1.Number, 0, 2, createHash(1))
+			requireFindLogEntry(block1.Number, 1, 3, createHash(2))
+			requireFindLogEntry(block1.Number, 2, 4, createHash(3))
+			requireFindLogEntry(block2.Number, 0, 5, createHash(4))
+			requireFindLogEntry(block2.Number, 3, 6, createHash(5))
+			requireFindLogEntry(block3.Number, 0, 7, createHash(0))
+			// Last log before the second search checkpoint and canonical hash
+			lastBeforeCheckpoint := uint32(searchCheckpointFrequency - 8)
+			requireFindLogEntry(block3.Number, lastBeforeCheckpoint, searchCheckpointFrequency-1, createHash(int(lastBeforeCheckpoint)))
+			requireFindLogEntry(block3.Number, lastBeforeCheckpoint+1, searchCheckpointFrequency+2, createHash(int(lastBeforeCheckpoint+1)))
+			requireFindLogEntry(block3.Number, uint32(block3LogCount-1), db.lastEntryIdx(), createHash(block3LogCount-1))
+
+			requireNotFound := func(blockNum uint64, logIdx uint32) {
+				_, err := db.FindLogEntry(blockNum, logIdx)
+				require.ErrorIs(t, err, ErrNotFound)
+			}
+			requireNotFound(49, 0)
+			requireNotFound(block1.Number, 3)
+			requireNotFound(51, 0)
+			requireNotFound(block2.Number, 1)
+			requireNotFound(block3.Number, uint32(block3LogCount))
+			requireNotFound(54, 0)
+		})
+}
+
 func TestGetBlockInfo(t *testing.T) {
 	t.Run("ReturnsEOFWhenEmpty", func(t *testing.T) {
 		runDBTest(t,
@@ -356,7 +530,7 @@ func TestGetBlockInfo(t *testing.T) {
 	t.Run("ReturnsEOFWhenRequestedBlockBeforeFirstSearchCheckpoint", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(11), Number: 11}, 500, 0)
+				err := db.AddLog(createHash(1), eth.BlockID{Hash: createHash(11), Number: 11}, 500, 0)
 				require.NoError(t, err)
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -369,7 +543,7 @@ func TestGetBlockInfo(t *testing.T) {
 		block := eth.BlockID{Hash: createHash(11), Number: 11}
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(1), block, 500, 0)
+				err := db.AddLog(createHash(1), block, 500, 0)
 				require.NoError(t, err)
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -384,7 +558,7 @@ func TestGetBlockInfo(t *testing.T) {
 			func(t *testing.T, db *DB, m *stubMetrics) {
 				for i := 1; i < searchCheckpointFrequency+3; i++ {
 					block := eth.BlockID{Hash: createHash(i), Number: uint64(i)}
-					err := db.AddLog(createTruncatedHash(i), block, uint64(i)*2, 0)
+					err := db.AddLog(createHash(i), block, uint64(i)*2, 0)
 					require.NoError(t, err)
 				}
 			},
@@ -430,6 +604,247 @@ func requireNotContains(t *testing.T, db *DB, blockNum uint64, logIdx uint32, lo
 	require.LessOrEqual(t, m.entriesReadForSearch, int64(searchCheckpointFrequency), "Should not need to read more than between two checkpoints")
 }
 
+func TestSearchCheckpoint(t *testing.T) {
+	const blockCount = 20
+	const logsPerBlock = 70
+	for _, frequency := range []int64{searchCheckpointFrequency, 100, minSearchCheckpointFrequency} {
+		frequency := frequency
+		t.Run(fmt.Sprintf("Frequency-%v", frequency), func(t *testing.T) {
+			runDBTestWithCheckpointFrequency(t, frequency,
+				func(t *testing.T, db *DB, m *stubMetrics) {
+					for blockNum := uint64(1); blockNum <= blockCount; blockNum++ {
+						block := eth.BlockID{Hash: createHash(int(blockNum)), Number: blockNum}
+						for i := 0; i < logsPerBlock; i++ {
+							require.NoError(t, db.AddLog(createHash(i), block, 500+blockNum, uint32(i)))
+						}
+					}
+				},
+				func(t *testing.T, db *DB, m *stubMetrics) {
+					// Checkpoints are written every frequency entries
+					checkpointCount := db.lastEntryIdx()/frequency + 1
+					require.Greater(t, checkpointCount, int64(4))
+					for idx := int64(0); idx <= db.lastEntryIdx(); idx++ {
+						entry, err := db.store.Read(idx)
+						require.NoError(t, err)
+						require.Equalf(t, idx%frequency == 0, entryType(entry) == typeSearchCheckpoint, "unexpected entry type at %v", idx)
+					}
+
+					for blockNum := uint64(1); blockNum <= blockCount; blockNum++ {
+						for _, logIdx := range []uint32{0, logsPerBlock / 2, logsPerBlock - 1} {
+							idx, err := db.ClosestSearchCheckpoint(blockNum, logIdx)
+							require.NoError(t, err)
+							require.Zero(t, idx%frequency)
+							checkpoint, err := db.readSearchCheckpoint(idx)
+							require.NoError(t, err)
+							// Must land on the last checkpoint at or before the requested log
+							require.True(t, checkpoint.blockNum < blockNum || (checkpoint.blockNum == blockNum && checkpoint.logIdx <= logIdx),
+								"checkpoint %v after block %v log %v", checkpoint, blockNum, logIdx)
+							if next := idx + frequency; next <= db.lastEntryIdx() {
+								nextCheckpoint, err := db.readSearchCheckpoint(next)
+								require.NoError(t, err)
+								require.True(t, nextCheckpoint.blockNum > blockNum || (nextCheckpoint.blockNum == blockNum && nextCheckpoint.logIdx > logIdx),
+									"later checkpoint %v at or before block %v log %v", nextCheckpoint, blockNum, logIdx)
+							}
+							requireContains(t, db, blockNum, logIdx, createHash(int(logIdx)))
+						}
+					}
+
+					// No checkpoint before the first log
+					_, err := db.ClosestSearchCheckpoint(0, 0)
+					require.ErrorIs(t, err, ErrNotFound)
+				})
+		})
+	}
+}
+
+func TestSearchCheckpointFrequency(t *testing.T) {
+	logger := testlog.Logger(t, log.LvlInfo)
+	// createDb writes enough logs for multiple search checkpoints at the given frequency
+	createDb := func(t *testing.T, frequency int64) string {
+		path := filepath.Join(t.TempDir(), "test.db")
+		db, err := NewFromFileWithCheckpointFrequency(logger, &stubMetrics{}, path, frequency)
+		require.NoError(t, err)
+		block := eth.BlockID{Hash: createHash(15), Number: 15}
+		for i := 0; i < 600; i++ {
+			require.NoError(t, db.AddLog(createHash(i), block, 5000, uint32(i)))
+		}
+		require.NoError(t, db.Close())
+		return path
+	}
+
+	t.Run("RejectTooLow", func(t *testing.T) {
+		_, err := NewFromFileWithCheckpointFrequency(logger, &stubMetrics{}, filepath.Join(t.TempDir(), "test.db"), minSearchCheckpointFrequency-1)
+		require.Error(t, err)
+	})
+
+	t.Run("RejectDifferentFrequency", func(t *testing.T) {
+		path := createDb(t, searchCheckpointFrequency)
+		for _, frequency := range []int64{100, searchCheckpointFrequency / 2, searchCheckpointFrequency * 3 / 2} {
+			_, err := NewFromFileWithCheckpointFrequency(logger, &stubMetrics{}, path, frequency)
+			require.ErrorIs(t, err, ErrIncompatibleCheckpointFrequency)
+			require.NotErrorIs(t, err, ErrDataCorruption)
+		}
+	})
+
+	t.Run("DetectCorruptSearchCheckpoint", func(t *testing.T) {
+		path := createDb(t, searchCheckpointFrequency)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		// Flip a bit in the block number of the last search checkpoint
+		data[2*searchCheckpointFrequency*entrydb.EntrySize+1] ^= 0x01
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+		_, err = NewFromFileWithCheckpointFrequency(logger, &stubMetrics{}, path, searchCheckpointFrequency)
+		require.ErrorIs(t, err, ErrDataCorruption)
+		require.NotErrorIs(t, err, ErrIncompatibleCheckpointFrequency)
+	})
+
+	t.Run("AcceptMultipleOfFrequency", func(t *testing.T) {
+		// Every entry searched at the larger frequency is a search checkpoint
+		path := createDb(t, searchCheckpointFrequency/2)
+		db, err := NewFromFileWithCheckpointFrequency(logger, &stubMetrics{}, path, searchCheckpointFrequency)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, db.Close())
+		})
+		for _, logIdx := range []uint32{0, 200, 300, 599} {
+			requireContains(t, db, 15, logIdx, createHash(int(logIdx)))
+		}
+	})
+}
+
+func TestCollisionCheck(t *testing.T) {
+	// Hashes that differ, but only after the truncated bytes
+	hash1 := createHash(1)
+	hash2 := createHash(1)
+	hash2[common.HashLength-1] = 0xff
+	require.NotEqual(t, hash1, hash2)
+	require.Equal(t, TruncateHash(hash1), TruncateHash(hash2))
+
+	block1 := eth.BlockID{Hash: createHash(15), Number: 15}
+	block2 := eth.BlockID{Hash: createHash(16), Number: 16}
+
+	createDb := func(t *testing.T) (*DB, *stubMetrics) {
+		m := &stubMetrics{}
+		db, err := NewFromFile(testlog.Logger(t, log.LvlInfo), m, filepath.Join(t.TempDir(), "test.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, db.Close())
+		})
+		return db, m
+	}
+
+	t.Run("DetectCollisionInSameBlock", func(t *testing.T) {
+		db, m := createDb(t)
+		db.EnableCollisionCheck()
+		require.NoError(t, db.AddLog(hash1, block1, 5000, 0))
+		require.NoError(t, db.AddLog(createHash(2), block1, 5000, 1))
+		require.Zero(t, m.hashCollisions)
+		require.NoError(t, db.AddLog(hash2, block1, 5000, 2))
+		require.EqualValues(t, 1, m.hashCollisions)
+		// The colliding log is still recorded
+		requireContains(t, db, block1.Number, 0, hash1)
+		requireContains(t, db, block1.Number, 2, hash2)
+	})
+
+	t.Run("IgnoreMatchingHashInDifferentBlock", func(t *testing.T) {
+		db, m := createDb(t)
+		db.EnableCollisionCheck()
+		require.NoError(t, db.AddLog(hash1, block1, 5000, 0))
+		require.NoError(t, db.AddLog(hash2, block2, 5002, 0))
+		require.Zero(t, m.hashCollisions)
+	})
+
+	t.Run("IgnoreIdenticalLogInSameBlock", func(t *testing.T) {
+		db, m := createDb(t)
+		db.EnableCollisionCheck()
+		require.NoError(t, db.AddLog(hash1, block1, 5000, 0))
+		require.NoError(t, db.AddLog(hash1, block1, 5000, 1))
+		require.Zero(t, m.hashCollisions)
+	})
+
+	t.Run("IgnoreRemovedLogsAfterRewind", func(t *testing.T) {
+		db, m := createDb(t)
+		db.EnableCollisionCheck()
+		require.NoError(t, db.AddLog(createHash(2), block1, 5000, 0))
+		require.NoError(t, db.AddLog(hash1, block2, 5002, 0))
+		require.NoError(t, db.Rewind(block1.Number))
+		// Reorg to a block with a different log
+		require.NoError(t, db.AddLog(hash2, block2, 5002, 0))
+		require.Zero(t, m.hashCollisions)
+	})
+
+	t.Run("IgnoreLogsThatFailedToWrite", func(t *testing.T) {
+		db, m := createDb(t)
+		db.EnableCollisionCheck()
+		require.NoError(t, db.AddLog(createHash(2), block1, 5000, 0))
+		store := &failingAppendStore{entryStore: db.store, fail: true}
+		db.store = store
+		require.ErrorIs(t, db.AddLog(hash1, block1, 5000, 1), errAppendFailed)
+		store.fail = false
+		require.NoError(t, db.AddLog(hash2, block1, 5000, 1))
+		require.Zero(t, m.hashCollisions)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		db, m := createDb(t)
+		require.NoError(t, db.AddLog(hash1, block1, 5000, 0))
+		require.NoError(t, db.AddLog(hash2, block1, 5000, 1))
+		require.Zero(t, m.hashCollisions)
+	})
+}
+
+var errAppendFailed = errors.New("append failed")
+
+// failingAppendStore fails to append any entries while fail is set
+type failingAppendStore struct {
+	entryStore
+	fail bool
+}
+
+func (s *failingAppendStore) Append(entries ...entrydb.Entry) error {
+	if s.fail {
+		return errAppendFailed
+	}
+	return s.entryStore.Append(entries...)
+}
+
+func TestDetectCorruptEntry(t *testing.T) {
+	// corruptEntry writes the logs, flips a single bit of the entry at entryIdx and reopens the database
+	corruptEntry := func(t *testing.T, addLogs func(db *DB), entryIdx int, offset int, bit byte) error {
+		path := filepath.Join(t.TempDir(), "test.db")
+		logger := testlog.Logger(t, log.LvlInfo)
+		db, err := NewFromFile(logger, &stubMetrics{}, path)
+		require.NoError(t, err)
+		addLogs(db)
+		require.NoError(t, db.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		data[entryIdx*entrydb.EntrySize+offset] ^= bit
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+
+		_, err = NewFromFile(logger, &stubMetrics{}, path)
+		return err
+	}
+
+	t.Run("InitiatingEventHash", func(t *testing.T) {
+		err := corruptEntry(t, func(db *DB) {
+			require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 0))
+			require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(15), Number: 15}, 5000, 1))
+		}, 3, 5, 0x01)
+		require.ErrorIs(t, err, ErrDataCorruption)
+	})
+
+	t.Run("SkipEntryType", func(t *testing.T) {
+		// Flipping the low bit turns the skip (type 5) into an executing check (type 4), which has no data to decode
+		err := corruptEntry(t, func(db *DB) {
+			require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(10), Number: 10}, 5000, 0))
+			require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(5000), Number: 5000}, 6000, 0))
+		}, 3, 0, 0x01)
+		require.ErrorIs(t, err, ErrDataCorruption)
+	})
+}
+
 func TestShouldRollBackInMemoryChangesOnWriteFailure(t *testing.T) {
 	t.Skip("TODO(optimism#10857)")
 }
@@ -458,10 +873,10 @@ func TestRewind(t *testing.T) {
 	t.Run("AfterLastBlock", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
-				require.NoError(t, db.AddLog(createTruncatedHash(3), eth.BlockID{Hash: createHash(51), Number: 51}, 502, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(4), eth.BlockID{Hash: createHash(74), Number: 74}, 700, 0))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
+				require.NoError(t, db.AddLog(createHash(3), eth.BlockID{Hash: createHash(51), Number: 51}, 502, 0))
+				require.NoError(t, db.AddLog(createHash(4), eth.BlockID{Hash: createHash(74), Number: 74}, 700, 0))
 				require.NoError(t, db.Rewind(75))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -475,8 +890,8 @@ func TestRewind(t *testing.T) {
 	t.Run("BeforeFirstBlock", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
 				require.NoError(t, db.Rewind(25))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -489,10 +904,10 @@ func TestRewind(t *testing.T) {
 	t.Run("AtFirstBlock", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(51), Number: 51}, 502, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(51), Number: 51}, 502, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(51), Number: 51}, 502, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(51), Number: 51}, 502, 1))
 				require.NoError(t, db.Rewind(50))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -507,12 +922,12 @@ func TestRewind(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
 				for i := uint32(0); m.entryCount < searchCheckpointFrequency; i++ {
-					require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, i))
+					require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, i))
 				}
 				require.EqualValues(t, searchCheckpointFrequency, m.entryCount)
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(51), Number: 51}, 502, 0))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(51), Number: 51}, 502, 0))
 				require.EqualValues(t, searchCheckpointFrequency+3, m.entryCount, "Should have inserted new checkpoint and extra log")
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(51), Number: 51}, 502, 1))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(51), Number: 51}, 502, 1))
 				require.NoError(t, db.Rewind(50))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -527,10 +942,10 @@ func TestRewind(t *testing.T) {
 	t.Run("BetweenLogEntries", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 1))
 				require.NoError(t, db.Rewind(55))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -544,12 +959,12 @@ func TestRewind(t *testing.T) {
 	t.Run("AtExistingLogEntry", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(59), Number: 59}, 500, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(59), Number: 59}, 500, 1))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 1))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(61), Number: 61}, 502, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(61), Number: 61}, 502, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(59), Number: 59}, 500, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(59), Number: 59}, 500, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(61), Number: 61}, 502, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(61), Number: 61}, 502, 1))
 				require.NoError(t, db.Rewind(60))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -565,12 +980,12 @@ func TestRewind(t *testing.T) {
 	t.Run("AtLastEntry", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 1))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(70), Number: 70}, 502, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(70), Number: 70}, 502, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(70), Number: 70}, 502, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(70), Number: 70}, 502, 1))
 				require.NoError(t, db.Rewind(70))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
@@ -583,23 +998,39 @@ func TestRewind(t *testing.T) {
 			})
 	})
 
+	t.Run("BeforeLargeBlockGap", func(t *testing.T) {
+		runDBTest(t,
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(50), Number: 50}, 500, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(51), Number: 5000}, 502, 0))
+				require.NoError(t, db.Rewind(50))
+			},
+			func(t *testing.T, db *DB, m *stubMetrics) {
+				require.EqualValues(t, 4, m.entryCount, "should remove skip entry with the block after it")
+				requireContains(t, db, 50, 0, createHash(1))
+				requireContains(t, db, 50, 1, createHash(2))
+				requireNotContains(t, db, 5000, 0, createHash(1))
+			})
+	})
+
 	t.Run("ReaddDeletedBlocks", func(t *testing.T) {
 		runDBTest(t,
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(59), Number: 59}, 500, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(59), Number: 59}, 500, 1))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 1))
-				require.NoError(t, db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(61), Number: 61}, 502, 0))
-				require.NoError(t, db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(61), Number: 61}, 502, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(59), Number: 59}, 500, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(59), Number: 59}, 500, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 1))
+				require.NoError(t, db.AddLog(createHash(1), eth.BlockID{Hash: createHash(61), Number: 61}, 502, 0))
+				require.NoError(t, db.AddLog(createHash(2), eth.BlockID{Hash: createHash(61), Number: 61}, 502, 1))
 				require.NoError(t, db.Rewind(60))
 			},
 			func(t *testing.T, db *DB, m *stubMetrics) {
-				err := db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(59), Number: 59}, 500, 1)
+				err := db.AddLog(createHash(2), eth.BlockID{Hash: createHash(59), Number: 59}, 500, 1)
 				require.ErrorIs(t, err, ErrLogOutOfOrder, "Cannot add block before rewound head")
-				err = db.AddLog(createTruncatedHash(2), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 1)
+				err = db.AddLog(createHash(2), eth.BlockID{Hash: createHash(60), Number: 60}, 502, 1)
 				require.ErrorIs(t, err, ErrLogOutOfOrder, "Cannot add block that was rewound to")
-				err = db.AddLog(createTruncatedHash(1), eth.BlockID{Hash: createHash(60), Number: 61}, 502, 0)
+				err = db.AddLog(createHash(1), eth.BlockID{Hash: createHash(60), Number: 61}, 502, 0)
 				require.NoError(t, err, "Can re-add deleted block")
 			})
 	})
@@ -608,6 +1039,7 @@ func TestRewind(t *testing.T) {
 type stubMetrics struct {
 	entryCount           int64
 	entriesReadForSearch int64
+	hashCollisions       int64
 }
 
 func (s *stubMetrics) RecordEntryCount(count int64) {
@@ -618,4 +1050,8 @@ func (s *stubMetrics) RecordSearchEntriesRead(count int64) {
 	s.entriesReadForSearch = count
 }
 
+func (s *stubMetrics) RecordTruncatedHashCollision() {
+	s.hashCollisions++
+}
+
 var _ Metrics = (*stubMetrics)(nil)
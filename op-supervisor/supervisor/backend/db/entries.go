@@ -3,6 +3,7 @@ package db
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"math"
 
 	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
@@ -22,10 +23,19 @@ func newSearchCheckpoint(blockNum uint64, logIdx uint32, timestamp uint64) searc
 	}
 }
 
+var searchCheckpointDecoders = entryDecoders[searchCheckpoint]{
+	typ:  typeSearchCheckpoint,
+	name: "search checkpoint",
+	versions: map[byte]func(data entrydb.Entry) (searchCheckpoint, error){
+		entryVersion0: decodeSearchCheckpointV0,
+	},
+}
+
 func newSearchCheckpointFromEntry(data entrydb.Entry) (searchCheckpoint, error) {
-	if data[0] != typeSearchCheckpoint {
-		return searchCheckpoint{}, fmt.Errorf("%w: attempting to decode search checkpoint but was type %v", ErrDataCorruption, data[0])
-	}
+	return searchCheckpointDecoders.decode(data)
+}
+
+func decodeSearchCheckpointV0(data entrydb.Entry) (searchCheckpoint, error) {
 	return searchCheckpoint{
 		blockNum:  binary.LittleEndian.Uint64(data[1:9]),
 		logIdx:    binary.LittleEndian.Uint32(data[9:13]),
@@ -37,11 +47,11 @@ func newSearchCheckpointFromEntry(data entrydb.Entry) (searchCheckpoint, error)
 // type 0: "search checkpoint" <type><uint64 block number: 8 bytes><uint32 event index offset: 4 bytes><uint64 timestamp: 8 bytes> = 20 bytes
 func (s searchCheckpoint) encode() entrydb.Entry {
 	var data entrydb.Entry
-	data[0] = typeSearchCheckpoint
+	data[0] = typeByte(typeSearchCheckpoint)
 	binary.LittleEndian.PutUint64(data[1:9], s.blockNum)
 	binary.LittleEndian.PutUint32(data[9:13], s.logIdx)
 	binary.LittleEndian.PutUint64(data[13:21], s.timestamp)
-	return data
+	return withChecksum(data)
 }
 
 type canonicalHash struct {
@@ -52,10 +62,19 @@ func newCanonicalHash(hash TruncatedHash) canonicalHash {
 	return canonicalHash{hash: hash}
 }
 
+var canonicalHashDecoders = entryDecoders[canonicalHash]{
+	typ:  typeCanonicalHash,
+	name: "canonical hash",
+	versions: map[byte]func(data entrydb.Entry) (canonicalHash, error){
+		entryVersion0: decodeCanonicalHashV0,
+	},
+}
+
 func newCanonicalHashFromEntry(data entrydb.Entry) (canonicalHash, error) {
-	if data[0] != typeCanonicalHash {
-		return canonicalHash{}, fmt.Errorf("%w: attempting to decode canonical hash but was type %v", ErrDataCorruption, data[0])
-	}
+	return canonicalHashDecoders.decode(data)
+}
+
+func decodeCanonicalHashV0(data entrydb.Entry) (canonicalHash, error) {
 	var truncated TruncatedHash
 	copy(truncated[:], data[1:21])
 	return newCanonicalHash(truncated), nil
@@ -63,34 +82,50 @@ func newCanonicalHashFromEntry(data entrydb.Entry) (canonicalHash, error) {
 
 func (c canonicalHash) encode() entrydb.Entry {
 	var entry entrydb.Entry
-	entry[0] = typeCanonicalHash
+	entry[0] = typeByte(typeCanonicalHash)
 	copy(entry[1:21], c.hash[:])
-	return entry
+	return withChecksum(entry)
 }
 
 type initiatingEvent struct {
-	blockDiff       uint8
-	incrementLogIdx bool
-	logHash         TruncatedHash
+	blockDiff uint8
+	logDiff   uint32
+	logHash   TruncatedHash
+}
+
+var initiatingEventDecoders = entryDecoders[initiatingEvent]{
+	typ:  typeInitiatingEvent,
+	name: "initiating event",
+	versions: map[byte]func(data entrydb.Entry) (initiatingEvent, error){
+		entryVersion0: decodeInitiatingEventV0,
+	},
 }
 
 func newInitiatingEventFromEntry(data entrydb.Entry) (initiatingEvent, error) {
-	if data[0] != typeInitiatingEvent {
-		return initiatingEvent{}, fmt.Errorf("%w: attempting to decode initiating event but was type %v", ErrDataCorruption, data[0])
-	}
+	return initiatingEventDecoders.decode(data)
+}
+
+func decodeInitiatingEventV0(data entrydb.Entry) (initiatingEvent, error) {
 	blockNumDiff := data[1]
 	flags := data[2]
+	skippedLogs := data[23]
+	logDiff := uint32(0)
+	if flags&eventFlagIncrementLogIdx != 0 {
+		logDiff = 1 + uint32(skippedLogs)
+	} else if skippedLogs != 0 {
+		return initiatingEvent{}, fmt.Errorf("%w: initiating event skips %v logs without incrementing log idx", ErrDataCorruption, skippedLogs)
+	}
 	return initiatingEvent{
-		blockDiff:       blockNumDiff,
-		incrementLogIdx: flags&eventFlagIncrementLogIdx != 0,
-		logHash:         TruncatedHash(data[3:23]),
+		blockDiff: blockNumDiff,
+		logDiff:   logDiff,
+		logHash:   TruncatedHash(data[3:23]),
 	}, nil
 }
 
 func newInitiatingEvent(pre logContext, blockNum uint64, logIdx uint32, logHash TruncatedHash) (initiatingEvent, error) {
 	blockDiff := blockNum - pre.blockNum
 	if blockDiff > math.MaxUint8 {
-		// TODO(optimism#10857): Need to find a way to support this.
+		// Larger gaps must be bridged by a skip entry before the initiating event
 		return initiatingEvent{}, fmt.Errorf("too many block skipped between %v and %v", pre.blockNum, blockNum)
 	}
 
@@ -99,31 +134,47 @@ func newInitiatingEvent(pre logContext, blockNum uint64, logIdx uint32, logHash
 		currLogIdx = 0
 	}
 	logDiff := logIdx - currLogIdx
-	if logDiff > 1 {
-		return initiatingEvent{}, fmt.Errorf("skipped logs between %v and %v", currLogIdx, logIdx)
+	if logDiff > maxInitiatingEventLogDiff {
+		// Larger gaps must be bridged by a skip entry before the initiating event
+		return initiatingEvent{}, fmt.Errorf("too many logs skipped between %v and %v", currLogIdx, logIdx)
 	}
 
 	return initiatingEvent{
-		blockDiff:       uint8(blockDiff),
-		incrementLogIdx: logDiff > 0,
-		logHash:         logHash,
+		blockDiff: uint8(blockDiff),
+		logDiff:   logDiff,
+		logHash:   logHash,
 	}, nil
 }
 
+// requiresSkip returns true if an initiating event for the log at blockNum and logIdx can't be encoded relative to pre
+// and a skip entry must be written first.
+func requiresSkip(pre logContext, blockNum uint64, logIdx uint32) bool {
+	blockDiff := blockNum - pre.blockNum
+	if blockDiff > math.MaxUint8 {
+		return true
+	}
+	if blockDiff > 0 {
+		return logIdx > maxInitiatingEventLogDiff
+	}
+	return logIdx-pre.logIdx > maxInitiatingEventLogDiff
+}
+
 // encode creates an initiating event entry
-// type 2: "initiating event" <type><blocknum diff: 1 byte><event flags: 1 byte><event-hash: 20 bytes> = 23 bytes
+// type 2: "initiating event" <type><blocknum diff: 1 byte><event flags: 1 byte><event-hash: 20 bytes><skipped logs: 1 byte> = 24 bytes
 func (i initiatingEvent) encode() entrydb.Entry {
 	var data entrydb.Entry
-	data[0] = typeInitiatingEvent
+	data[0] = typeByte(typeInitiatingEvent)
 	data[1] = i.blockDiff
 	flags := byte(0)
-	if i.incrementLogIdx {
+	if i.logDiff > 0 {
 		// Set flag to indicate log idx needs to be incremented (ie we're not directly after a checkpoint)
 		flags = flags | eventFlagIncrementLogIdx
+		// Any increment beyond the first is recorded as the number of skipped logs
+		data[23] = byte(i.logDiff - 1)
 	}
 	data[2] = flags
 	copy(data[3:23], i.logHash[:])
-	return data
+	return withChecksum(data)
 }
 
 func (i initiatingEvent) postContext(pre logContext) logContext {
@@ -134,8 +185,199 @@ func (i initiatingEvent) postContext(pre logContext) logContext {
 	if i.blockDiff > 0 {
 		post.logIdx = 0
 	}
-	if i.incrementLogIdx {
-		post.logIdx++
-	}
+	post.logIdx += i.logDiff
 	return post
 }
+
+type executingLink struct {
+	chain     uint32
+	blockNum  uint64
+	logIdx    uint32
+	timestamp uint64
+}
+
+func newExecutingLink(chain uint32, blockNum uint64, logIdx uint32, timestamp uint64) (executingLink, error) {
+	if logIdx > maxExecutingLinkLogIdx {
+		return executingLink{}, fmt.Errorf("log idx %v is too large to record in executing link", logIdx)
+	}
+	return executingLink{
+		chain:     chain,
+		blockNum:  blockNum,
+		logIdx:    logIdx,
+		timestamp: timestamp,
+	}, nil
+}
+
+var executingLinkDecoders = entryDecoders[executingLink]{
+	typ:  typeExecutingLink,
+	name: "executing link",
+	versions: map[byte]func(data entrydb.Entry) (executingLink, error){
+		entryVersion0: decodeExecutingLinkV0,
+	},
+}
+
+func newExecutingLinkFromEntry(data entrydb.Entry) (executingLink, error) {
+	return executingLinkDecoders.decode(data)
+}
+
+func decodeExecutingLinkV0(data entrydb.Entry) (executingLink, error) {
+	return executingLink{
+		chain:     binary.LittleEndian.Uint32(data[1:5]),
+		blockNum:  binary.LittleEndian.Uint64(data[5:13]),
+		logIdx:    uint32(data[13]) | uint32(data[14])<<8 | uint32(data[15])<<16,
+		timestamp: binary.LittleEndian.Uint64(data[16:24]),
+	}, nil
+}
+
+// encode creates an executing link entry, referencing the initiating event of an executing message
+// type 3: "executing link" <type><chain: 4 bytes><blocknum: 8 bytes><event index: 3 bytes><uint64 timestamp: 8 bytes> = 24 bytes
+func (e executingLink) encode() entrydb.Entry {
+	var data entrydb.Entry
+	data[0] = typeByte(typeExecutingLink)
+	binary.LittleEndian.PutUint32(data[1:5], e.chain)
+	binary.LittleEndian.PutUint64(data[5:13], e.blockNum)
+	data[13] = byte(e.logIdx)
+	data[14] = byte(e.logIdx >> 8)
+	data[15] = byte(e.logIdx >> 16)
+	binary.LittleEndian.PutUint64(data[16:24], e.timestamp)
+	return withChecksum(data)
+}
+
+type executingCheck struct {
+	hash TruncatedHash
+}
+
+func newExecutingCheck(hash TruncatedHash) executingCheck {
+	return executingCheck{hash: hash}
+}
+
+var executingCheckDecoders = entryDecoders[executingCheck]{
+	typ:  typeExecutingCheck,
+	name: "executing check",
+	versions: map[byte]func(data entrydb.Entry) (executingCheck, error){
+		entryVersion0: decodeExecutingCheckV0,
+	},
+}
+
+func newExecutingCheckFromEntry(data entrydb.Entry) (executingCheck, error) {
+	return executingCheckDecoders.decode(data)
+}
+
+func decodeExecutingCheckV0(data entrydb.Entry) (executingCheck, error) {
+	return newExecutingCheck(TruncatedHash(data[1:21])), nil
+}
+
+// encode creates an executing check entry
+// type 4: "executing check" <type><event-hash: 20 bytes> = 21 bytes
+func (e executingCheck) encode() entrydb.Entry {
+	var data entrydb.Entry
+	data[0] = typeByte(typeExecutingCheck)
+	copy(data[1:21], e.hash[:])
+	return withChecksum(data)
+}
+
+type skip struct {
+	blockDiff uint64
+	logIdx    uint32
+}
+
+func newSkip(pre logContext, blockNum uint64, logIdx uint32) skip {
+	return skip{
+		blockDiff: blockNum - pre.blockNum,
+		logIdx:    logIdx,
+	}
+}
+
+var skipDecoders = entryDecoders[skip]{
+	typ:  typeSkip,
+	name: "skip",
+	versions: map[byte]func(data entrydb.Entry) (skip, error){
+		entryVersion0: decodeSkipV0,
+	},
+}
+
+func newSkipFromEntry(data entrydb.Entry) (skip, error) {
+	return skipDecoders.decode(data)
+}
+
+func decodeSkipV0(data entrydb.Entry) (skip, error) {
+	return skip{
+		blockDiff: binary.LittleEndian.Uint64(data[1:9]),
+		logIdx:    binary.LittleEndian.Uint32(data[9:13]),
+	}, nil
+}
+
+// encode creates a skip entry
+// type 5: "skip" <type><uint64 blocknum diff: 8 bytes><uint32 event index: 4 bytes> = 13 bytes
+func (s skip) encode() entrydb.Entry {
+	var data entrydb.Entry
+	data[0] = typeByte(typeSkip)
+	binary.LittleEndian.PutUint64(data[1:9], s.blockDiff)
+	binary.LittleEndian.PutUint32(data[9:13], s.logIdx)
+	return withChecksum(data)
+}
+
+// postContext moves to the log the skip was written for.
+// Like after a search checkpoint, the following initiating event neither adds a block diff nor increments the log idx.
+func (s skip) postContext(pre logContext) logContext {
+	return logContext{
+		blockNum: pre.blockNum + s.blockDiff,
+		logIdx:   s.logIdx,
+	}
+}
+
+// entryDecoders decodes entries of a single type, using the decoder for the layout of the entry's version.
+// Changing the layout of an entry type adds a decoder for the new version, so existing entries are still decoded
+// with the layout they were written in.
+type entryDecoders[T any] struct {
+	typ      byte
+	name     string
+	versions map[byte]func(data entrydb.Entry) (T, error)
+}
+
+// decode verifies the entry's checksum and type, then decodes it with the decoder for its version.
+// Returns ErrUnsupportedVersion if there is no decoder for the entry's version.
+func (d entryDecoders[T]) decode(data entrydb.Entry) (T, error) {
+	var empty T
+	if err := verifyChecksum(data); err != nil {
+		return empty, err
+	}
+	if entryType(data) != d.typ {
+		return empty, fmt.Errorf("%w: attempting to decode %v but was type %v", ErrDataCorruption, d.name, entryType(data))
+	}
+	version := entryVersion(data)
+	decodeVersion, ok := d.versions[version]
+	if !ok {
+		return empty, fmt.Errorf("%w: %v has version %v", ErrUnsupportedVersion, d.name, version)
+	}
+	return decodeVersion(data)
+}
+
+// withChecksum sets the checksum of the entry data
+func withChecksum(data entrydb.Entry) entrydb.Entry {
+	binary.LittleEndian.PutUint32(data[entryDataSize:], crc32.ChecksumIEEE(data[:entryDataSize]))
+	return data
+}
+
+// verifyChecksum returns an ErrDataCorruption error if the entry data doesn't match its checksum
+func verifyChecksum(data entrydb.Entry) error {
+	expected := binary.LittleEndian.Uint32(data[entryDataSize:])
+	actual := crc32.ChecksumIEEE(data[:entryDataSize])
+	if expected != actual {
+		return fmt.Errorf("%w: entry checksum %x does not match data checksum %x", ErrDataCorruption, expected, actual)
+	}
+	return nil
+}
+
+// typeByte combines the entry type with the current entry version
+func typeByte(typ byte) byte {
+	return currentEntryVersion<<entryVersionShift | typ
+}
+
+func entryType(data entrydb.Entry) byte {
+	return data[0] & entryTypeMask
+}
+
+func entryVersion(data entrydb.Entry) byte {
+	return data[0] >> entryVersionShift
+}
@@ -0,0 +1,206 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/stretchr/testify/require"
+)
+
+type entryDecodeTest struct {
+	name   string
+	entry  entrydb.Entry
+	decode func(entrydb.Entry) error
+}
+
+// entryDecodeTests returns a valid encoded entry of each type along with the function to decode it
+func entryDecodeTests(t *testing.T) []entryDecodeTest {
+	link, err := newExecutingLink(10, 1234, 5, 5678)
+	require.NoError(t, err)
+	return []entryDecodeTest{
+		{
+			name:  "SearchCheckpoint",
+			entry: newSearchCheckpoint(1234, 5, 5678).encode(),
+			decode: func(entry entrydb.Entry) error {
+				_, err := newSearchCheckpointFromEntry(entry)
+				return err
+			},
+		},
+		{
+			name:  "CanonicalHash",
+			entry: newCanonicalHash(createTruncatedHash(1)).encode(),
+			decode: func(entry entrydb.Entry) error {
+				_, err := newCanonicalHashFromEntry(entry)
+				return err
+			},
+		},
+		{
+			name:  "InitiatingEvent",
+			entry: initiatingEvent{blockDiff: 3, logDiff: 5, logHash: createTruncatedHash(2)}.encode(),
+			decode: func(entry entrydb.Entry) error {
+				_, err := newInitiatingEventFromEntry(entry)
+				return err
+			},
+		},
+		{
+			name:  "ExecutingLink",
+			entry: link.encode(),
+			decode: func(entry entrydb.Entry) error {
+				_, err := newExecutingLinkFromEntry(entry)
+				return err
+			},
+		},
+		{
+			name:  "ExecutingCheck",
+			entry: newExecutingCheck(createTruncatedHash(3)).encode(),
+			decode: func(entry entrydb.Entry) error {
+				_, err := newExecutingCheckFromEntry(entry)
+				return err
+			},
+		},
+		{
+			name:  "Skip",
+			entry: newSkip(logContext{blockNum: 10, logIdx: 2}, 1000, 7).encode(),
+			decode: func(entry entrydb.Entry) error {
+				_, err := newSkipFromEntry(entry)
+				return err
+			},
+		},
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	for _, test := range entryDecodeTests(t) {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			require.NoError(t, test.decode(test.entry))
+			for i := 0; i < entrydb.EntrySize; i++ {
+				corrupt := test.entry
+				corrupt[i] ^= 0x10
+				require.ErrorIsf(t, test.decode(corrupt), ErrDataCorruption, "did not detect corruption of byte %v", i)
+			}
+		})
+	}
+}
+
+func TestRejectWrongType(t *testing.T) {
+	tests := entryDecodeTests(t)
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			for _, other := range tests {
+				if entryType(other.entry) == entryType(test.entry) {
+					continue
+				}
+				// Valid checksum so the entry is rejected for its type rather than the checksum
+				err := test.decode(other.entry)
+				require.ErrorIs(t, err, ErrDataCorruption)
+				require.ErrorContains(t, err, fmt.Sprintf("but was type %v", entryType(other.entry)))
+			}
+		})
+	}
+}
+
+func TestExecutingLink(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		tests := []executingLink{
+			{},
+			{chain: 10, blockNum: 1234, logIdx: 5, timestamp: 5678},
+			{chain: math.MaxUint32, blockNum: math.MaxUint64, logIdx: maxExecutingLinkLogIdx, timestamp: math.MaxUint64},
+		}
+		for _, expected := range tests {
+			link, err := newExecutingLink(expected.chain, expected.blockNum, expected.logIdx, expected.timestamp)
+			require.NoError(t, err)
+			require.Equal(t, expected, link)
+
+			entry := link.encode()
+			require.Equal(t, typeExecutingLink, entry[0])
+			actual, err := newExecutingLinkFromEntry(entry)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		}
+	})
+
+	t.Run("RejectLogIdxTooLarge", func(t *testing.T) {
+		_, err := newExecutingLink(10, 1234, maxExecutingLinkLogIdx+1, 5678)
+		require.Error(t, err)
+	})
+
+	t.Run("RejectWrongType", func(t *testing.T) {
+		link, err := newExecutingLink(10, 1234, 5, 5678)
+		require.NoError(t, err)
+		for _, typ := range []byte{typeSearchCheckpoint, typeCanonicalHash, typeInitiatingEvent, typeExecutingCheck, typeSkip} {
+			entry := link.encode()
+			entry[0] = typeByte(typ)
+			// Valid checksum so the entry is rejected for its type rather than the checksum
+			entry = withChecksum(entry)
+			_, err := newExecutingLinkFromEntry(entry)
+			require.ErrorIs(t, err, ErrDataCorruption)
+			require.ErrorContains(t, err, fmt.Sprintf("attempting to decode executing link but was type %v", typ))
+		}
+	})
+}
+
+func TestEntryVersion(t *testing.T) {
+	// Hypothetical future layout of initiating events, with the log hash moved to the start of the entry
+	decodeInitiatingEventV1 := func(data entrydb.Entry) (initiatingEvent, error) {
+		return initiatingEvent{
+			blockDiff: data[21],
+			logDiff:   uint32(data[22]),
+			logHash:   TruncatedHash(data[1:21]),
+		}, nil
+	}
+	encodeInitiatingEventV1 := func(evt initiatingEvent) entrydb.Entry {
+		var data entrydb.Entry
+		data[0] = (entryVersion0+1)<<entryVersionShift | typeInitiatingEvent
+		copy(data[1:21], evt.logHash[:])
+		data[21] = evt.blockDiff
+		data[22] = byte(evt.logDiff)
+		return withChecksum(data)
+	}
+	v0Decoders := initiatingEventDecoders
+	v1Decoders := entryDecoders[initiatingEvent]{
+		typ:  typeInitiatingEvent,
+		name: "initiating event",
+		versions: map[byte]func(data entrydb.Entry) (initiatingEvent, error){
+			entryVersion0:     decodeInitiatingEventV0,
+			entryVersion0 + 1: decodeInitiatingEventV1,
+		},
+	}
+	expected := initiatingEvent{blockDiff: 3, logDiff: 5, logHash: createTruncatedHash(2)}
+
+	t.Run("DecodePreviousVersion", func(t *testing.T) {
+		v0Entry := expected.encode()
+		require.Equal(t, entryVersion0, entryVersion(v0Entry))
+		evt, err := v1Decoders.decode(v0Entry)
+		require.NoError(t, err)
+		require.Equal(t, expected, evt)
+	})
+
+	t.Run("DecodeNewVersion", func(t *testing.T) {
+		evt, err := v1Decoders.decode(encodeInitiatingEventV1(expected))
+		require.NoError(t, err)
+		require.Equal(t, expected, evt)
+	})
+
+	t.Run("RejectNewVersionWithPreviousDecoder", func(t *testing.T) {
+		_, err := v0Decoders.decode(encodeInitiatingEventV1(expected))
+		require.ErrorIs(t, err, ErrUnsupportedVersion)
+		require.NotErrorIs(t, err, ErrDataCorruption)
+	})
+
+	for _, test := range entryDecodeTests(t) {
+		test := test
+		t.Run("RejectUnsupportedVersion-"+test.name, func(t *testing.T) {
+			require.Equal(t, currentEntryVersion, entryVersion(test.entry))
+			entry := test.entry
+			entry[0] = (currentEntryVersion+1)<<entryVersionShift | entryType(entry)
+			entry = withChecksum(entry)
+			err := test.decode(entry)
+			require.ErrorIs(t, err, ErrUnsupportedVersion)
+			require.NotErrorIs(t, err, ErrDataCorruption)
+		})
+	}
+}
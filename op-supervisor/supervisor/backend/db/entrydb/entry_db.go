@@ -8,7 +8,7 @@ import (
 )
 
 const (
-	EntrySize = 24
+	EntrySize = 28
 )
 
 type Entry [EntrySize]byte
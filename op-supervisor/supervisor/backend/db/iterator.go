@@ -3,13 +3,101 @@ package db
 import (
 	"fmt"
 	"io"
+
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
 )
 
+// ResolvedEvent is an initiating event with its position resolved from the entries before it.
+type ResolvedEvent struct {
+	BlockNum uint64
+	LogIdx   uint32
+	// Timestamp of the block containing the event. Block timestamps are only recorded in search checkpoints, so the
+	// timestamp is only known for events in the same block as the last search checkpoint, as indicated by HasTimestamp.
+	Timestamp    uint64
+	HasTimestamp bool
+	LogHash      TruncatedHash
+}
+
+// replayer reconstructs the log context by applying entries in order, starting from a search checkpoint.
+type replayer struct {
+	current    logContext
+	checkpoint searchCheckpoint
+}
+
+func newReplayer(start searchCheckpoint) replayer {
+	return replayer{
+		current: logContext{
+			blockNum: start.blockNum,
+			logIdx:   start.logIdx,
+		},
+		checkpoint: start,
+	}
+}
+
+// apply advances the log context past entry.
+// If entry is an initiating event, it is returned with its position resolved, otherwise nil is returned.
+func (r *replayer) apply(entry entrydb.Entry) (*ResolvedEvent, error) {
+	// Verify the checksum before trusting the type byte to select how the entry is handled
+	if err := verifyChecksum(entry); err != nil {
+		return nil, err
+	}
+	switch entryType(entry) {
+	case typeSearchCheckpoint:
+		current, err := newSearchCheckpointFromEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse search checkpoint: %w", err)
+		}
+		r.current.blockNum = current.blockNum
+		r.current.logIdx = current.logIdx
+		r.checkpoint = current
+	case typeCanonicalHash:
+		// Skip, but still check the entry isn't corrupt
+		if _, err := newCanonicalHashFromEntry(entry); err != nil {
+			return nil, fmt.Errorf("failed to parse canonical hash: %w", err)
+		}
+	case typeInitiatingEvent:
+		evt, err := newInitiatingEventFromEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse initiating event: %w", err)
+		}
+		r.current = evt.postContext(r.current)
+		resolved := &ResolvedEvent{
+			BlockNum: r.current.blockNum,
+			LogIdx:   r.current.logIdx,
+			LogHash:  evt.logHash,
+		}
+		if r.current.blockNum == r.checkpoint.blockNum {
+			resolved.Timestamp = r.checkpoint.timestamp
+			resolved.HasTimestamp = true
+		}
+		return resolved, nil
+	case typeSkip:
+		s, err := newSkipFromEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse skip: %w", err)
+		}
+		r.current = s.postContext(r.current)
+	case typeExecutingCheck:
+		// TODO(optimism#10857): Handle this properly
+		if _, err := newExecutingCheckFromEntry(entry); err != nil {
+			return nil, fmt.Errorf("failed to parse executing check: %w", err)
+		}
+	case typeExecutingLink:
+		// TODO(optimism#10857): Handle this properly
+		if _, err := newExecutingLinkFromEntry(entry); err != nil {
+			return nil, fmt.Errorf("failed to parse executing link: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown entry type %v", ErrDataCorruption, entryType(entry))
+	}
+	return nil, nil
+}
+
 type iterator struct {
 	db           *DB
 	nextEntryIdx int64
 
-	current logContext
+	replay replayer
 
 	entriesRead int64
 }
@@ -19,42 +107,64 @@ func (i *iterator) NextLog() (blockNum uint64, logIdx uint32, evtHash TruncatedH
 		entryIdx := i.nextEntryIdx
 		entry, err := i.db.store.Read(entryIdx)
 		if err != nil {
-			outErr = fmt.Errorf("failed to read entry %v: %w", i, err)
+			outErr = fmt.Errorf("failed to read entry %v: %w", entryIdx, err)
 			return
 		}
 		i.nextEntryIdx++
 		i.entriesRead++
-		switch entry[0] {
-		case typeSearchCheckpoint:
-			current, err := newSearchCheckpointFromEntry(entry)
-			if err != nil {
-				outErr = fmt.Errorf("failed to parse search checkpoint at idx %v: %w", entryIdx, err)
-				return
-			}
-			i.current.blockNum = current.blockNum
-			i.current.logIdx = current.logIdx
-		case typeCanonicalHash:
-			// Skip
-		case typeInitiatingEvent:
-			evt, err := newInitiatingEventFromEntry(entry)
-			if err != nil {
-				outErr = fmt.Errorf("failed to parse initiating event at idx %v: %w", entryIdx, err)
-				return
-			}
-			i.current = evt.postContext(i.current)
-			blockNum = i.current.blockNum
-			logIdx = i.current.logIdx
-			evtHash = evt.logHash
+		evt, err := i.replay.apply(entry)
+		if err != nil {
+			outErr = fmt.Errorf("failed to apply entry at idx %v: %w", entryIdx, err)
 			return
-		case typeExecutingCheck:
-		// TODO(optimism#10857): Handle this properly
-		case typeExecutingLink:
-		// TODO(optimism#10857): Handle this properly
-		default:
-			outErr = fmt.Errorf("unknown entry type at idx %v %v", entryIdx, entry[0])
+		}
+		if evt != nil {
+			blockNum = evt.BlockNum
+			logIdx = evt.LogIdx
+			evtHash = evt.LogHash
 			return
 		}
 	}
 	outErr = io.EOF
 	return
 }
+
+// EntriesIterator resolves the position of the initiating events in a range of entries read from the DB.
+type EntriesIterator struct {
+	entries []entrydb.Entry
+	nextIdx int
+
+	replay replayer
+}
+
+// NewEntriesIterator creates an iterator over the initiating events in entries.
+// The entries must start with a search checkpoint, as every range read from a search checkpoint entry of the DB does.
+func NewEntriesIterator(entries []entrydb.Entry) (*EntriesIterator, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: no search checkpoint to start from", ErrDataCorruption)
+	}
+	start, err := newSearchCheckpointFromEntry(entries[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse starting search checkpoint: %w", err)
+	}
+	return &EntriesIterator{
+		entries: entries,
+		nextIdx: 1,
+		replay:  newReplayer(start),
+	}, nil
+}
+
+// Next returns the next initiating event, or io.EOF when there are no more entries
+func (i *EntriesIterator) Next() (ResolvedEvent, error) {
+	for i.nextIdx < len(i.entries) {
+		idx := i.nextIdx
+		i.nextIdx++
+		evt, err := i.replay.apply(i.entries[idx])
+		if err != nil {
+			return ResolvedEvent{}, fmt.Errorf("failed to apply entry at idx %v: %w", idx, err)
+		}
+		if evt != nil {
+			return *evt, nil
+		}
+	}
+	return ResolvedEvent{}, io.EOF
+}
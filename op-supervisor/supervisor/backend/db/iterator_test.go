@@ -0,0 +1,197 @@
+package db
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum-optimism/optimism/op-supervisor/supervisor/backend/db/entrydb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntriesIterator(t *testing.T) {
+	t.Run("NoEntries", func(t *testing.T) {
+		_, err := NewEntriesIterator(nil)
+		require.ErrorIs(t, err, ErrDataCorruption)
+	})
+
+	t.Run("NotStartingAtSearchCheckpoint", func(t *testing.T) {
+		_, err := NewEntriesIterator([]entrydb.Entry{newCanonicalHash(createTruncatedHash(10)).encode()})
+		require.ErrorIs(t, err, ErrDataCorruption)
+	})
+
+	t.Run("NoEvents", func(t *testing.T) {
+		i, err := NewEntriesIterator([]entrydb.Entry{
+			newSearchCheckpoint(10, 0, 100).encode(),
+			newCanonicalHash(createTruncatedHash(10)).encode(),
+		})
+		require.NoError(t, err)
+		_, err = i.Next()
+		require.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("MixedEntries", func(t *testing.T) {
+		link, err := newExecutingLink(5, 1000, 3, 900)
+		require.NoError(t, err)
+		entries := []entrydb.Entry{
+			newSearchCheckpoint(10, 2, 100).encode(),
+			newCanonicalHash(createTruncatedHash(10)).encode(),
+			// Block 10, starting at the checkpoint log idx
+			initiatingEvent{logHash: createTruncatedHash(1)}.encode(),
+			initiatingEvent{logDiff: 1, logHash: createTruncatedHash(2)}.encode(),
+			link.encode(),
+			initiatingEvent{logDiff: 3, logHash: createTruncatedHash(3)}.encode(),
+			// Block 12
+			initiatingEvent{blockDiff: 2, logHash: createTruncatedHash(4)}.encode(),
+			initiatingEvent{logDiff: 1, logHash: createTruncatedHash(5)}.encode(),
+			// Block 5012 via skip
+			newSkip(logContext{blockNum: 12, logIdx: 1}, 5012, 4).encode(),
+			initiatingEvent{logHash: createTruncatedHash(6)}.encode(),
+			// Block 5013 via search checkpoint
+			newSearchCheckpoint(5013, 0, 500).encode(),
+			newCanonicalHash(createTruncatedHash(5013)).encode(),
+			initiatingEvent{logHash: createTruncatedHash(7)}.encode(),
+			initiatingEvent{logDiff: 1, logHash: createTruncatedHash(8)}.encode(),
+		}
+		expected := []ResolvedEvent{
+			{BlockNum: 10, LogIdx: 2, Timestamp: 100, HasTimestamp: true, LogHash: createTruncatedHash(1)},
+			{BlockNum: 10, LogIdx: 3, Timestamp: 100, HasTimestamp: true, LogHash: createTruncatedHash(2)},
+			{BlockNum: 10, LogIdx: 6, Timestamp: 100, HasTimestamp: true, LogHash: createTruncatedHash(3)},
+			{BlockNum: 12, LogIdx: 0, LogHash: createTruncatedHash(4)},
+			{BlockNum: 12, LogIdx: 1, LogHash: createTruncatedHash(5)},
+			{BlockNum: 5012, LogIdx: 4, LogHash: createTruncatedHash(6)},
+			{BlockNum: 5013, LogIdx: 0, Timestamp: 500, HasTimestamp: true, LogHash: createTruncatedHash(7)},
+			{BlockNum: 5013, LogIdx: 1, Timestamp: 500, HasTimestamp: true, LogHash: createTruncatedHash(8)},
+		}
+
+		i, err := NewEntriesIterator(entries)
+		require.NoError(t, err)
+		for _, expectedEvt := range expected {
+			evt, err := i.Next()
+			require.NoError(t, err)
+			require.Equal(t, expectedEvt, evt)
+		}
+		_, err = i.Next()
+		require.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("ZeroTimestamp", func(t *testing.T) {
+		i, err := NewEntriesIterator([]entrydb.Entry{
+			newSearchCheckpoint(10, 0, 0).encode(),
+			newCanonicalHash(createTruncatedHash(10)).encode(),
+			initiatingEvent{logHash: createTruncatedHash(1)}.encode(),
+			initiatingEvent{blockDiff: 1, logHash: createTruncatedHash(2)}.encode(),
+		})
+		require.NoError(t, err)
+		evt, err := i.Next()
+		require.NoError(t, err)
+		require.True(t, evt.HasTimestamp)
+		require.Zero(t, evt.Timestamp)
+		evt, err = i.Next()
+		require.NoError(t, err)
+		require.False(t, evt.HasTimestamp)
+	})
+
+	t.Run("EntriesReadFromDB", func(t *testing.T) {
+		db, err := NewFromFile(testlog.Logger(t, log.LvlInfo), &stubMetrics{}, filepath.Join(t.TempDir(), "test.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, db.Close())
+		})
+		block1 := eth.BlockID{Hash: createHash(15), Number: 15}
+		block2 := eth.BlockID{Hash: createHash(5000), Number: 5000}
+		require.NoError(t, db.AddLog(createHash(1), block1, 100, 0))
+		require.NoError(t, db.AddLog(createHash(2), block1, 100, 3))
+		require.NoError(t, db.AddLog(createHash(3), block2, 600, 0))
+
+		var entries []entrydb.Entry
+		for idx := int64(0); idx <= db.lastEntryIdx(); idx++ {
+			entry, err := db.store.Read(idx)
+			require.NoError(t, err)
+			entries = append(entries, entry)
+		}
+		i, err := NewEntriesIterator(entries)
+		require.NoError(t, err)
+		expected := []ResolvedEvent{
+			{BlockNum: 15, LogIdx: 0, Timestamp: 100, HasTimestamp: true, LogHash: createTruncatedHash(1)},
+			{BlockNum: 15, LogIdx: 3, Timestamp: 100, HasTimestamp: true, LogHash: createTruncatedHash(2)},
+			{BlockNum: 5000, LogIdx: 0, LogHash: createTruncatedHash(3)},
+		}
+		for _, expectedEvt := range expected {
+			evt, err := i.Next()
+			require.NoError(t, err)
+			require.Equal(t, expectedEvt, evt)
+		}
+		_, err = i.Next()
+		require.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("CorruptEntry", func(t *testing.T) {
+		corrupt := initiatingEvent{logDiff: 1, logHash: createTruncatedHash(2)}.encode()
+		corrupt[5] ^= 0x01
+		i, err := NewEntriesIterator([]entrydb.Entry{
+			newSearchCheckpoint(10, 0, 100).encode(),
+			newCanonicalHash(createTruncatedHash(10)).encode(),
+			initiatingEvent{logHash: createTruncatedHash(1)}.encode(),
+			corrupt,
+		})
+		require.NoError(t, err)
+		_, err = i.Next()
+		require.NoError(t, err)
+		_, err = i.Next()
+		require.ErrorIs(t, err, ErrDataCorruption)
+	})
+
+	t.Run("UnknownEntryType", func(t *testing.T) {
+		var unknown entrydb.Entry
+		unknown[0] = typeSkip + 1
+		unknown = withChecksum(unknown)
+		i, err := NewEntriesIterator([]entrydb.Entry{newSearchCheckpoint(10, 0, 100).encode(), unknown})
+		require.NoError(t, err)
+		_, err = i.Next()
+		require.ErrorIs(t, err, ErrDataCorruption)
+		require.ErrorContains(t, err, "unknown entry type")
+	})
+
+	t.Run("UnsupportedVersion", func(t *testing.T) {
+		for _, entry := range []entrydb.Entry{
+			newExecutingCheck(createTruncatedHash(2)).encode(),
+			initiatingEvent{logHash: createTruncatedHash(1)}.encode(),
+		} {
+			entry[0] = (currentEntryVersion+1)<<entryVersionShift | entryType(entry)
+			entry = withChecksum(entry)
+			i, err := NewEntriesIterator([]entrydb.Entry{
+				newSearchCheckpoint(10, 0, 100).encode(),
+				newCanonicalHash(createTruncatedHash(10)).encode(),
+				entry,
+			})
+			require.NoError(t, err)
+			_, err = i.Next()
+			require.ErrorIs(t, err, ErrUnsupportedVersion)
+		}
+	})
+
+	t.Run("CorruptEntryType", func(t *testing.T) {
+		skipEntry := newSkip(logContext{blockNum: 10, logIdx: 0}, 5000, 0).encode()
+		// Executing checks carry no data to decode, so must still be rejected by the checksum
+		skipToExecutingCheck := skipEntry
+		skipToExecutingCheck[0] = typeByte(typeExecutingCheck)
+		// Type bits no longer match an entry type
+		skipToUnknown := skipEntry
+		skipToUnknown[0] = typeByte(typeSkip + 1)
+		for _, corrupt := range []entrydb.Entry{skipToExecutingCheck, skipToUnknown} {
+			i, err := NewEntriesIterator([]entrydb.Entry{
+				newSearchCheckpoint(10, 0, 100).encode(),
+				newCanonicalHash(createTruncatedHash(10)).encode(),
+				corrupt,
+				initiatingEvent{logHash: createTruncatedHash(1)}.encode(),
+			})
+			require.NoError(t, err)
+			_, err = i.Next()
+			require.ErrorIs(t, err, ErrDataCorruption)
+		}
+	})
+}